@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"claudecompanion/internal/state"
+	"claudecompanion/internal/statusline"
+)
+
+// staleCacheAge is how long the cached usage is shown while the app doesn't respond
+const staleCacheAge = 10 * time.Minute
+
+// claudeCodeInput is the part of the JSON Claude Code passes on stdin that we use
+type claudeCodeInput struct {
+	Model struct {
+		DisplayName string `json:"display_name"`
+	} `json:"model"`
+}
+
+// cmdClaudeStatusline prints the quota line for the statusLine setting of Claude Code
+// Claude Code runs it on every update, so the usage is cached for a few seconds
+// and the local API is asked with a short timeout
+func cmdClaudeStatusline(args []string) int {
+	var opts cliOptions
+	fs := flag.NewFlagSet("claude-statusline", flag.ContinueOnError)
+	fs.IntVar(&opts.port, "port", 0, "Порт локального API (по умолчанию server_port из config.yaml)")
+	fs.DurationVar(&opts.timeout, "timeout", 300*time.Millisecond, "Сколько ждать ответа приложения")
+	cacheTTL := fs.Duration("cache", 10*time.Second, "Сколько использовать сохранённый ответ, 0 - не кэшировать")
+	windows := fs.String("windows", "five_hour,seven_day", "Окна через запятую, all - все")
+	showModel := fs.Bool("model", false, "Показывать модель из данных Claude Code")
+	noColor := fs.Bool("no-color", false, "Без цветов ANSI (также переменная NO_COLOR)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return exitUsage
+	}
+
+	lineOpts := statusline.ClaudeCodeOptions{Color: !*noColor && os.Getenv("NO_COLOR") == ""}
+	if *windows != "all" {
+		lineOpts.Windows = strings.Split(*windows, ",")
+	}
+	if input, ok := readClaudeCodeInput(); ok && *showModel && input.Model.DisplayName != "" {
+		lineOpts.Prefix = input.Model.DisplayName + " · "
+	}
+
+	c, err := opts.connect()
+	if err != nil {
+		fmt.Println(statusline.ClaudeCodeOffline(lineOpts))
+		return exitOK
+	}
+
+	cachePath := filepath.Join(os.TempDir(), fmt.Sprintf("claudecompanion-statusline-%d.json", opts.port))
+	snap, age := readUsageCache(cachePath)
+	if snap == nil || age > *cacheTTL {
+		if fresh, err := c.Usage(); err == nil {
+			snap, age = fresh, 0
+			writeUsageCache(cachePath, fresh)
+		}
+	}
+
+	if snap == nil || age > staleCacheAge {
+		fmt.Println(statusline.ClaudeCodeOffline(lineOpts))
+		return exitOK
+	}
+	fmt.Println(statusline.ClaudeCode(snap, lineOpts))
+	return exitOK
+}
+
+// readClaudeCodeInput reads the session JSON from stdin unless stdin is a terminal
+func readClaudeCodeInput() (claudeCodeInput, bool) {
+	var input claudeCodeInput
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return input, false
+	}
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+	if err != nil || json.Unmarshal(data, &input) != nil {
+		return input, false
+	}
+	return input, true
+}
+
+// readUsageCache returns the cached usage and its age, nil if there is none
+func readUsageCache(path string) (*state.Snapshot, time.Duration) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0
+	}
+	var snap state.Snapshot
+	if json.Unmarshal(data, &snap) != nil {
+		return nil, 0
+	}
+	return &snap, time.Since(info.ModTime())
+}
+
+// writeUsageCache saves the usage atomically, several Claude Code sessions may read it at once
+func writeUsageCache(path string, snap *state.Snapshot) {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
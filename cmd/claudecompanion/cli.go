@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/client"
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/server"
+	"claudecompanion/internal/state"
+)
+
+// Output formats of CLI commands
+const (
+	formatHuman = "human" // Readable text in Russian
+	formatJSON  = "json"  // Raw local API response
+	formatLine  = "line"  // A single line for scripts and status bars
+)
+
+// Exit codes of CLI commands
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const cliUsage = `Использование: claudecompanion [--headless]
+       claudecompanion <команда> [флаги]
+
+Команды (работают с запущенным экземпляром через локальный API):
+  status              текущие лимиты, прогноз и состояние опроса
+  refresh             опросить API сейчас и показать результат
+  pause               приостановить автоматический опрос
+  resume              возобновить автоматический опрос
+  history [--hours N] замеры, ошибки и уведомления за N часов (24)
+  notify-test         отправить тестовое уведомление во все каналы
+  config validate [путь]
+                      проверить config.yaml (не требует запущенного экземпляра)
+  statusline [--format waybar|i3blocks|polybar|tmux|plain] [--follow]
+                      остаток квоты для панелей задач, --follow печатает
+                      строку при каждом изменении
+  claude-statusline   строка квоты для statusLine в Claude Code
+  mcp                 MCP-сервер на stdin/stdout для агентов
+
+Общие флаги:
+  --format human|json|line   формат вывода (human)
+  --port N                   порт локального API (server_port из config.yaml)
+
+Коды выхода: 0 - успех, 1 - ошибка, 2 - неверные аргументы
+`
+
+// isCommand returns true if name is a CLI command rather than a flag of the app
+func isCommand(name string) bool {
+	switch name {
+	case "status", "refresh", "pause", "resume", "history", "notify-test", "config", "statusline", "claude-statusline", "mcp", "help":
+		return true
+	}
+	return false
+}
+
+// runCommand runs a CLI command and returns the exit code
+// The logger isn't initialized, so commands don't write to the app log
+func runCommand(name string, args []string) int {
+	switch name {
+	case "status":
+		return cmdStatus(args)
+	case "refresh":
+		return cmdRefresh(args)
+	case "pause", "resume", "notify-test":
+		return cmdControl(name, args)
+	case "history":
+		return cmdHistory(args)
+	case "config":
+		return cmdConfig(args)
+	case "statusline":
+		return cmdStatusline(args)
+	case "claude-statusline":
+		return cmdClaudeStatusline(args)
+	case "mcp":
+		return cmdMCP(args)
+	default:
+		fmt.Print(cliUsage)
+		return exitOK
+	}
+}
+
+// cliOptions are flags shared by commands that talk to a running instance
+type cliOptions struct {
+	format  string
+	port    int
+	timeout time.Duration // 0 = request timeout of the config plus a margin
+	cfg     config.Config // Loaded by connect
+}
+
+// newFlagSet creates the flag set of a command with the shared flags
+func newFlagSet(name string, opts *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.format, "format", formatHuman, "Формат вывода: human, json или line")
+	fs.IntVar(&opts.port, "port", 0, "Порт локального API (по умолчанию server_port из config.yaml)")
+	return fs
+}
+
+// parse parses the command arguments, returns false on usage errors
+func (o *cliOptions) parse(fs *flag.FlagSet, args []string) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	switch o.format {
+	case formatHuman, formatJSON, formatLine:
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестный формат %q: human, json или line\n", o.format)
+		return false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Лишние аргументы: %s\n", strings.Join(fs.Args(), " "))
+		return false
+	}
+	return true
+}
+
+// connect creates a local API client using config.yaml and the pairing token next to it
+func (o *cliOptions) connect() (*client.Client, error) {
+	cfg, path, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+	o.cfg = cfg
+	if o.port == 0 {
+		o.port = cfg.ServerPort
+	}
+	// Only control actions need the token, reading works without it
+	token, _ := server.ReadPairingToken(filepath.Join(filepath.Dir(path), server.PairingTokenFile))
+	// Refresh responds after the poll, which may take the whole request timeout
+	if o.timeout == 0 {
+		o.timeout = time.Duration(cfg.RequestTimeoutSeconds)*time.Second + 5*time.Second
+	}
+	return client.New(o.port, token, o.timeout), nil
+}
+
+// fail prints a command error and returns the exit code
+func (o *cliOptions) fail(err error) int {
+	if errors.Is(err, client.ErrNotRunning) {
+		fmt.Fprintf(os.Stderr, "ClaudeCompanion не запущен: порт %d не отвечает\n", o.port)
+	} else {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+	}
+	return exitError
+}
+
+// cmdStatus prints the current usage
+func cmdStatus(args []string) int {
+	var opts cliOptions
+	if !opts.parse(newFlagSet("status", &opts), args) {
+		return exitUsage
+	}
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	snap, err := c.Usage()
+	if err != nil {
+		return opts.fail(err)
+	}
+	printSnapshot(os.Stdout, opts.format, snap)
+	return exitOK
+}
+
+// cmdRefresh polls the API now and prints the result
+func cmdRefresh(args []string) int {
+	var opts cliOptions
+	if !opts.parse(newFlagSet("refresh", &opts), args) {
+		return exitUsage
+	}
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	if err := c.Control(server.ActionRefresh); err != nil {
+		return opts.fail(err)
+	}
+	snap, err := c.Usage()
+	if err != nil {
+		return opts.fail(err)
+	}
+	printSnapshot(os.Stdout, opts.format, snap)
+	if snap.Error != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// controlMessages are printed after successful control actions
+var controlMessages = map[string]string{
+	server.ActionPause:      "Автоматический опрос приостановлен",
+	server.ActionResume:     "Автоматический опрос возобновлён",
+	server.ActionNotifyTest: "Тестовое уведомление отправлено",
+}
+
+// cmdControl performs a control action without output of its own
+func cmdControl(action string, args []string) int {
+	var opts cliOptions
+	if !opts.parse(newFlagSet(action, &opts), args) {
+		return exitUsage
+	}
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	if err := c.Control(action); err != nil {
+		return opts.fail(err)
+	}
+	switch opts.format {
+	case formatJSON:
+		printJSON(os.Stdout, map[string]string{"status": "ok", "action": action})
+	case formatLine:
+		fmt.Println("ok")
+	default:
+		fmt.Println(controlMessages[action])
+	}
+	return exitOK
+}
+
+// cmdHistory prints samples, errors and notifications of the last hours
+func cmdHistory(args []string) int {
+	var opts cliOptions
+	fs := newFlagSet("history", &opts)
+	hours := fs.Int("hours", 24, "За сколько последних часов (до 168)")
+	if !opts.parse(fs, args) {
+		return exitUsage
+	}
+	if *hours < 1 {
+		fmt.Fprintln(os.Stderr, "--hours должно быть положительным")
+		return exitUsage
+	}
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	history, err := c.History(*hours)
+	if err != nil {
+		return opts.fail(err)
+	}
+	printHistory(os.Stdout, opts.format, *hours, history)
+	return exitOK
+}
+
+// cmdConfig runs config subcommands (only validate for now)
+func cmdConfig(args []string) int {
+	const usage = "Использование: claudecompanion config validate [--format human|json|line] [путь]"
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitUsage
+	}
+
+	var opts cliOptions
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.StringVar(&opts.format, "format", formatHuman, "Формат вывода: human, json или line")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitUsage
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		var err error
+		if path, err = config.Path(); err != nil {
+			return opts.fail(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return opts.fail(err)
+	}
+	problems := config.Validate(data)
+
+	switch opts.format {
+	case formatJSON:
+		printJSON(os.Stdout, map[string]interface{}{
+			"path":     path,
+			"valid":    len(problems) == 0,
+			"problems": append([]string{}, problems...),
+		})
+	case formatLine:
+		if len(problems) == 0 {
+			fmt.Println("ok")
+		} else {
+			fmt.Println(strings.Join(problems, "; "))
+		}
+	default:
+		if len(problems) == 0 {
+			fmt.Printf("Конфигурация в порядке: %s\n", path)
+		} else {
+			fmt.Printf("Ошибки в %s:\n", path)
+			for _, problem := range problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// printJSON writes v as indented JSON
+func printJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// printSnapshot writes the usage state in the given format
+func printSnapshot(w io.Writer, format string, snap *state.Snapshot) {
+	switch format {
+	case formatJSON:
+		printJSON(w, snap)
+	case formatLine:
+		fmt.Fprintln(w, snapshotLine(snap))
+	default:
+		printSnapshotHuman(w, snap)
+	}
+}
+
+// snapshotLine formats all windows in one line: "5ч 58% ↻16:05 | 7д 29% ↻20.10 09:00"
+func snapshotLine(snap *state.Snapshot) string {
+	parts := make([]string, 0, len(snap.Windows))
+	for _, win := range snap.Windows {
+		parts = append(parts, fmt.Sprintf("%s %d%% ↻%s", win.Label, win.Remaining, api.FormatResetTime(win.ResetsAt)))
+	}
+	line := strings.Join(parts, " | ")
+	if line == "" {
+		line = "—"
+	}
+	if snap.Paused {
+		line = "⏸ " + line
+	}
+	if snap.Error != nil {
+		line += " ⚠"
+	}
+	return line
+}
+
+// printSnapshotHuman writes the usage state as readable text
+func printSnapshotHuman(w io.Writer, snap *state.Snapshot) {
+	if snap.Demo {
+		fmt.Fprintln(w, "Демо-режим")
+	}
+	if !snap.HasContext {
+		fmt.Fprintln(w, "Ожидаю куки от расширения")
+	} else if len(snap.Windows) == 0 {
+		fmt.Fprintln(w, "Нет данных о лимитах")
+	}
+
+	for _, win := range snap.Windows {
+		marker := "  "
+		if win.Name == snap.PrimaryWindow {
+			marker = "▸ "
+		}
+		fmt.Fprintf(w, "%s%-10s осталось %3d%%, сброс %s\n", marker, win.Label, win.Remaining, api.FormatResetTime(win.ResetsAt))
+	}
+	for i := range snap.Forecast {
+		f := &snap.Forecast[i]
+		if f.BeforeReset {
+			fmt.Fprintf(w, "Прогноз %s: %s\n", api.WindowLabel(f.Window), f.TooltipLine())
+		}
+	}
+
+	if snap.Error != nil {
+		fmt.Fprintf(w, "Ошибка (%d подряд): %s\n", snap.ConsecutiveErrors, snap.Error.Message)
+		if snap.Error.Hint != "" {
+			fmt.Fprintln(w, snap.Error.Hint)
+		}
+	}
+	if snap.LastSuccess != nil {
+		fmt.Fprintf(w, "Обновлено: %s\n", snap.LastSuccess.Local().Format("15:04 02.01"))
+	}
+	if snap.Paused {
+		fmt.Fprintln(w, "Автоматический опрос приостановлен")
+	} else if snap.NextPoll != nil {
+		fmt.Fprintf(w, "Следующий опрос: %s\n", snap.NextPoll.Local().Format("15:04:05"))
+	}
+}
+
+// printHistory writes the history in the given format
+func printHistory(w io.Writer, format string, hours int, history *state.History) {
+	switch format {
+	case formatJSON:
+		printJSON(w, history)
+		return
+	case formatLine:
+		fmt.Fprintf(w, "samples=%d errors=%d notifications=%d\n",
+			len(history.Samples), len(history.Errors), len(history.Notifications))
+		return
+	}
+
+	fmt.Fprintf(w, "За %dч: замеров %d, ошибок %d, уведомлений %d\n",
+		hours, len(history.Samples), len(history.Errors), len(history.Notifications))
+	if n := len(history.Samples); n > 0 {
+		last := history.Samples[n-1]
+		fmt.Fprintf(w, "Последний замер %s: %s\n", last.Time.Local().Format("15:04 02.01"), sampleLine(last))
+	}
+	if len(history.Errors) > 0 {
+		fmt.Fprintln(w, "\nОшибки:")
+		for _, e := range history.Errors {
+			fmt.Fprintf(w, "  %s  %s: %s\n", e.Time.Local().Format("15:04 02.01"), e.Kind, e.Message)
+		}
+	}
+	if len(history.Notifications) > 0 {
+		fmt.Fprintln(w, "\nУведомления:")
+		for _, n := range history.Notifications {
+			message := strings.ReplaceAll(n.Message, "\n", " ")
+			fmt.Fprintf(w, "  %s  %s — %s\n", n.Time.Local().Format("15:04 02.01"), n.Title, message)
+		}
+	}
+}
+
+// sampleLine formats the remaining quota of a sample: "5ч 42% | 7д 71%"
+func sampleLine(sample state.Sample) string {
+	windows := make([]api.UsageWindow, 0, len(sample.Utilization))
+	for name, utilization := range sample.Utilization {
+		windows = append(windows, api.UsageWindow{Name: name, Utilization: utilization})
+	}
+	api.SortWindows(windows)
+
+	parts := make([]string, 0, len(windows))
+	for i := range windows {
+		parts = append(parts, fmt.Sprintf("%s %d%%", windows[i].Label(), windows[i].Remaining()))
+	}
+	return strings.Join(parts, " | ")
+}
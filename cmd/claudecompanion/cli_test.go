@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"claudecompanion/internal/forecast"
+	"claudecompanion/internal/state"
+)
+
+func TestMain(m *testing.M) {
+	// Formatters print local time, pin it so expectations don't depend on the machine
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
+
+// inHours returns a time n hours from now, truncated to a minute
+func inHours(n float64) *time.Time {
+	t := time.Now().Add(time.Duration(n * float64(time.Hour))).Truncate(time.Minute)
+	return &t
+}
+
+// captureOutput returns what fn writes to os.Stdout and os.Stderr
+func captureOutput(t *testing.T, fn func()) (stdout, stderr string) {
+	capture := func(target **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *target
+		*target = w
+		done := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			done <- string(data)
+		}()
+		return func() string {
+			w.Close()
+			*target = orig
+			return <-done
+		}
+	}
+	stopOut := capture(&os.Stdout)
+	stopErr := capture(&os.Stderr)
+	fn()
+	return stopOut(), stopErr()
+}
+
+func testSnapshot() *state.Snapshot {
+	five, week := inHours(2), inHours(72)
+	exhausts := five.Add(-80 * time.Minute)
+	lastSuccess := time.Date(2026, 1, 2, 14, 41, 0, 0, time.UTC)
+	return &state.Snapshot{
+		Windows: []state.WindowState{
+			{Name: "five_hour", Label: "5ч", Utilization: 58, Remaining: 42, ResetsAt: five},
+			{Name: "seven_day", Label: "7д", Utilization: 29, Remaining: 71, ResetsAt: week},
+		},
+		PrimaryWindow: "five_hour",
+		Remaining:     42,
+		Forecast: []forecast.Forecast{
+			{Window: "five_hour", BurnRate: 30, ExhaustsAt: &exhausts, ResetsAt: five, BeforeReset: true},
+			{Window: "seven_day", BurnRate: 1, ResetsAt: week},
+		},
+		LastSuccess: &lastSuccess,
+		HasContext:  true,
+	}
+}
+
+func TestSnapshotLine(t *testing.T) {
+	base := testSnapshot()
+	five := base.Windows[0].ResetsAt.Format("15:04")
+	week := base.Windows[1].ResetsAt.Format("02.01 15:04")
+
+	tests := []struct {
+		name   string
+		modify func(s *state.Snapshot)
+		want   string
+	}{
+		{"windows", func(*state.Snapshot) {}, "5ч 42% ↻" + five + " | 7д 71% ↻" + week},
+		{"no windows", func(s *state.Snapshot) { s.Windows = nil }, "—"},
+		{"paused", func(s *state.Snapshot) { s.Windows = s.Windows[:1]; s.Paused = true }, "⏸ 5ч 42% ↻" + five},
+		{"error", func(s *state.Snapshot) { s.Windows = nil; s.Error = &state.ErrorState{Kind: "network"} }, "— ⚠"},
+		{"unknown reset", func(s *state.Snapshot) { s.Windows = s.Windows[:1]; s.Windows[0].ResetsAt = nil }, "5ч 42% ↻—"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := testSnapshot()
+			tt.modify(snap)
+			if got := snapshotLine(snap); got != tt.want {
+				t.Errorf("snapshotLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSnapshotHuman(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *state.Snapshot)
+		want    []string
+		wantNot []string
+	}{
+		{
+			name:   "usage",
+			modify: func(*state.Snapshot) {},
+			want: []string{
+				"▸ 5ч         осталось  42%, сброс ",
+				"  7д         осталось  71%, сброс ",
+				"Прогноз 5ч: Кончится в ",
+				"за 1ч20м до сброса",
+				"Обновлено: 14:41 02.01",
+			},
+			wantNot: []string{"Прогноз 7д", "Ошибка", "Ожидаю куки"},
+		},
+		{
+			name: "error with hint",
+			modify: func(s *state.Snapshot) {
+				s.Error = &state.ErrorState{Message: "Сессия истекла", Hint: "Откройте claude.ai"}
+				s.ConsecutiveErrors = 3
+			},
+			want: []string{"Ошибка (3 подряд): Сессия истекла\nОткройте claude.ai\n"},
+		},
+		{
+			name:    "waiting for context",
+			modify:  func(s *state.Snapshot) { *s = state.Snapshot{} },
+			want:    []string{"Ожидаю куки от расширения"},
+			wantNot: []string{"Нет данных", "Обновлено"},
+		},
+		{
+			name:   "no windows yet",
+			modify: func(s *state.Snapshot) { s.Windows = nil; s.Forecast = nil; s.LastSuccess = nil },
+			want:   []string{"Нет данных о лимитах"},
+		},
+		{
+			name: "paused wins over next poll",
+			modify: func(s *state.Snapshot) {
+				s.Paused = true
+				s.NextPoll = inHours(1)
+			},
+			want:    []string{"Автоматический опрос приостановлен"},
+			wantNot: []string{"Следующий опрос"},
+		},
+		{
+			name:   "next poll",
+			modify: func(s *state.Snapshot) { s.NextPoll = &time.Time{} },
+			want:   []string{"Следующий опрос: 00:00:00"},
+		},
+		{
+			name:   "demo",
+			modify: func(s *state.Snapshot) { s.Demo = true },
+			want:   []string{"Демо-режим\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := testSnapshot()
+			tt.modify(snap)
+			var out bytes.Buffer
+			printSnapshot(&out, formatHuman, snap)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(out.String(), unwanted) {
+					t.Errorf("output contains %q:\n%s", unwanted, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPrintSnapshotFormats(t *testing.T) {
+	snap := testSnapshot()
+
+	var line bytes.Buffer
+	printSnapshot(&line, formatLine, snap)
+	if line.String() != snapshotLine(snap)+"\n" {
+		t.Errorf("line format = %q", line.String())
+	}
+
+	var out bytes.Buffer
+	printSnapshot(&out, formatJSON, snap)
+	var decoded state.Snapshot
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("json format is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded.Windows) != 2 || decoded.Remaining != 42 || decoded.PrimaryWindow != "five_hour" {
+		t.Errorf("json format = %+v", decoded)
+	}
+}
+
+func TestSampleLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		utilization map[string]float64
+		want        string
+	}{
+		{"empty", nil, ""},
+		{"tooltip order", map[string]float64{"seven_day_opus": 10, "seven_day": 29, "extra_usage": 0, "five_hour": 58}, "5ч 42% | 7д 71% | Доп. 100% | 7д Opus 90%"},
+		{"over the limit", map[string]float64{"five_hour": 104}, "5ч 0%"},
+	}
+
+	for _, tt := range tests {
+		if got := sampleLine(state.Sample{Utilization: tt.utilization}); got != tt.want {
+			t.Errorf("%s: sampleLine() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	history := &state.History{
+		Samples: []state.Sample{
+			{Time: time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC), Utilization: map[string]float64{"five_hour": 10}},
+			{Time: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), Utilization: map[string]float64{"seven_day": 29, "five_hour": 58}},
+		},
+		Errors: []state.ErrorRecord{
+			{Time: time.Date(2026, 1, 2, 9, 30, 0, 0, time.UTC), Kind: "rate_limited", Message: "HTTP 429"},
+		},
+		Notifications: []state.NotificationRecord{
+			{Time: time.Date(2026, 1, 2, 9, 45, 0, 0, time.UTC), Title: "Низкая квота", Message: "Пора\nотдохнуть"},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{formatLine, []string{"samples=2 errors=1 notifications=1\n"}},
+		{formatHuman, []string{
+			"За 6ч: замеров 2, ошибок 1, уведомлений 1\n",
+			"Последний замер 10:00 02.01: 5ч 42% | 7д 71%\n",
+			"Ошибки:\n  09:30 02.01  rate_limited: HTTP 429\n",
+			"Уведомления:\n  09:45 02.01  Низкая квота — Пора отдохнуть\n",
+		}},
+		{formatJSON, []string{`"kind": "rate_limited"`, `"five_hour": 58`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			printHistory(&out, tt.format, 6, history)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+
+	var empty bytes.Buffer
+	printHistory(&empty, formatHuman, 24, &state.History{})
+	if empty.String() != "За 24ч: замеров 0, ошибок 0, уведомлений 0\n" {
+		t.Errorf("empty history = %q", empty.String())
+	}
+}
+
+func TestCmdConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(valid, []byte("poll_interval_seconds: 120\n"), 0600)
+	os.WriteFile(invalid, []byte("poll_interval_secnds: 120\n"), 0600)
+
+	const usage = "Использование: claudecompanion config validate [--format human|json|line] [путь]\n"
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "no subcommand", args: nil, wantCode: exitUsage, wantStderr: usage},
+		{name: "unknown subcommand", args: []string{"check"}, wantCode: exitUsage, wantStderr: usage},
+		{name: "two paths", args: []string{"validate", valid, invalid}, wantCode: exitUsage, wantStderr: usage},
+		{name: "valid", args: []string{"validate", "--format", "line", valid}, wantCode: exitOK, wantStdout: "ok\n"},
+		{name: "invalid", args: []string{"validate", invalid}, wantCode: exitError, wantStdout: "Ошибки в " + invalid},
+		{name: "missing file", args: []string{"validate", filepath.Join(dir, "missing.yaml")}, wantCode: exitError, wantStderr: "Ошибка: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			stdout, stderr := captureOutput(t, func() { code = cmdConfig(tt.args) })
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+
+	"claudecompanion/internal/logger"
+	"claudecompanion/internal/server"
+)
+
+// handleControl performs actions requested by CLI commands via POST /control/<action>
+func (a *App) handleControl(action string) error {
+	switch action {
+	case server.ActionRefresh:
+		if !a.apiClient.HasContext() {
+			return errors.New("no context from the browser extension yet")
+		}
+		logger.Info("Refresh requested from the command line")
+		a.pollManual()
+	case server.ActionPause:
+		a.setPaused(true)
+	case server.ActionResume:
+		a.setPaused(false)
+	case server.ActionNotifyTest:
+		logger.Info("Test notification requested from the command line")
+		a.notifier.NotifyTest()
+	default:
+		return server.ErrUnknownAction
+	}
+	return nil
+}
+
+// setPaused stops or resumes automatic polling; manual refresh still works while paused
+func (a *App) setPaused(paused bool) {
+	if a.paused.Swap(paused) == paused {
+		return
+	}
+	a.usageState.SetPaused(paused)
+	if paused {
+		logger.Info("Automatic polling paused")
+	} else {
+		logger.Info("Automatic polling resumed")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"claudecompanion/internal/logger"
+)
+
+// Display shows the usage state to the user: the tray icon, or the log in headless mode
+type Display interface {
+	UpdateIcon(value int, hasError bool, tooltip string)
+	UpdateTargetURL(url string)
+	SetBrowserPath(path string)
+}
+
+// headlessDisplay logs status changes instead of drawing a tray icon
+type headlessDisplay struct {
+	mu      sync.Mutex
+	tooltip string
+}
+
+// UpdateIcon logs the tooltip when it changes
+func (d *headlessDisplay) UpdateIcon(value int, hasError bool, tooltip string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if tooltip == d.tooltip {
+		return
+	}
+	d.tooltip = tooltip
+	logger.Info("Status: %s", strings.Join(strings.Split(strings.ReplaceAll(tooltip, "\r\n", "\n"), "\n"), " | "))
+}
+
+// UpdateTargetURL is a no-op: there is no menu to open Claude.ai from
+func (d *headlessDisplay) UpdateTargetURL(url string) {}
+
+// SetBrowserPath is a no-op: there is no menu to open a browser from
+func (d *headlessDisplay) SetBrowserPath(path string) {}
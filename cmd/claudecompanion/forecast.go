@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/forecast"
+	"claudecompanion/internal/logger"
+)
+
+// forecastLookback converts lookback_minutes to a duration
+func forecastLookback(cfg config.Config) time.Duration {
+	return time.Duration(cfg.Forecast.LookbackMinutes) * time.Minute
+}
+
+// updateForecast adds a successful poll to the estimator, publishes forecasts of all windows
+// and returns the forecast of the primary window (nil if there isn't enough data yet)
+func (a *App) updateForecast(cfg config.Config, usage *api.UsageResponse, at time.Time) *forecast.Forecast {
+	a.estimator.SetLookback(forecastLookback(cfg))
+	a.estimator.AddUsage(usage, at)
+	a.usageState.SetForecast(a.estimator.Forecasts(usage))
+
+	primary := usage.PrimaryWindow()
+	if primary == nil {
+		return nil
+	}
+	return a.estimator.Forecast(primary.Name)
+}
+
+// checkForecastNotification warns once per window period when the quota runs out before reset
+func (a *App) checkForecastNotification(cfg config.Config, usage *api.UsageResponse, f *forecast.Forecast) {
+	primary := usage.PrimaryWindow()
+	if primary == nil {
+		return
+	}
+
+	// A new period (window reset or another window selected) allows a new warning
+	if primary.Name != a.forecastWindow || primary.ResetsAt == nil || a.forecastResetsAt == nil ||
+		!primary.ResetsAt.Equal(*a.forecastResetsAt) {
+		a.notifier.ResetForecastNotification()
+		a.forecastWindow = primary.Name
+		a.forecastResetsAt = primary.ResetsAt
+	}
+
+	if !cfg.Forecast.Notify || f == nil || !f.BeforeReset || primary.Remaining() == 0 {
+		return
+	}
+	if f.Lead() < time.Duration(cfg.Forecast.MinLeadMinutes)*time.Minute {
+		return
+	}
+
+	message := fmt.Sprintf("При текущем темпе (%.0f%%/ч) лимит %s кончится в %s, за %s до сброса в %s",
+		f.BurnRate, primary.Label(), api.FormatResetTime(f.ExhaustsAt), api.FormatDuration(f.Lead()), api.FormatResetTime(f.ResetsAt))
+	logger.Warning("Forecast: %s runs out at %s, %s before reset", primary.Name,
+		f.ExhaustsAt.Local().Format("15:04"), f.Lead().Round(time.Minute))
+	a.notifier.NotifyForecast(message)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/history"
+	"claudecompanion/internal/logger"
+	"claudecompanion/internal/state"
+)
+
+// historyRetention converts retention_days to a duration
+func historyRetention(cfg config.Config) time.Duration {
+	return time.Duration(cfg.History.RetentionDays) * 24 * time.Hour
+}
+
+// applyHistoryConfig opens, closes or updates the history store according to cfg
+func (a *App) applyHistoryConfig(cfg config.Config) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	if cfg.History.Disabled {
+		if a.historyStore != nil {
+			a.historyStore.Close()
+			a.historyStore = nil
+			logger.Info("History disabled")
+		}
+		return
+	}
+
+	if a.historyStore != nil {
+		a.historyStore.SetRetention(historyRetention(cfg))
+		return
+	}
+
+	path := filepath.Join(filepath.Dir(a.configMgr.GetPath()), history.FileName)
+	store, err := history.Open(path, historyRetention(cfg))
+	if err != nil {
+		logger.Warning("Failed to open history: %v", err)
+		return
+	}
+	a.historyStore = store
+	logger.Info("History: %s (retention %d days)", path, cfg.History.RetentionDays)
+
+	a.restoreHistory(store)
+}
+
+// restoreHistory loads recent records into the in-memory state so the dashboard survives restarts
+func (a *App) restoreHistory(store *history.Store) {
+	records, err := store.Query(time.Now().Add(-state.MaxHistoryAge))
+	if err != nil {
+		logger.Warning("Failed to read history: %v", err)
+		return
+	}
+
+	var samples []state.Sample
+	var errors []state.ErrorRecord
+	for _, rec := range records {
+		if rec.IsError() {
+			errors = append(errors, state.ErrorRecord{
+				Time:       rec.Time,
+				Kind:       rec.Error.Kind,
+				StatusCode: rec.Error.StatusCode,
+				Message:    rec.Error.Message,
+			})
+			continue
+		}
+		utilization := make(map[string]float64, len(rec.Windows))
+		for _, w := range rec.Windows {
+			utilization[w.Name] = w.Utilization
+			// Recent samples let the forecast work right after a restart
+			a.estimator.Add(w.Name, w.Utilization, w.ResetsAt, rec.Time)
+		}
+		samples = append(samples, state.Sample{Time: rec.Time, Utilization: utilization})
+	}
+
+	a.usageState.RestoreHistory(samples, errors)
+	logger.Info("History restored: %d samples, %d errors", len(samples), len(errors))
+}
+
+// recordUsage appends a successful poll to the history file
+func (a *App) recordUsage(usage *api.UsageResponse, at time.Time) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	if a.historyStore == nil {
+		return
+	}
+	if err := a.historyStore.AppendUsage(a.apiClient.OrganizationID(), usage, at); err != nil {
+		logger.Warning("Failed to write history: %v", err)
+	}
+}
+
+// recordError appends a failed poll to the history file
+func (a *App) recordError(fetchErr *api.FetchError, at time.Time) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	if a.historyStore == nil {
+		return
+	}
+	if err := a.historyStore.AppendError(a.apiClient.OrganizationID(), fetchErr, at); err != nil {
+		logger.Warning("Failed to write history: %v", err)
+	}
+}
+
+// closeHistory closes the history file on shutdown
+func (a *App) closeHistory() {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	if a.historyStore != nil {
+		a.historyStore.Close()
+		a.historyStore = nil
+	}
+}
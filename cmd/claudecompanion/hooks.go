@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/hooks"
+	"claudecompanion/internal/logger"
+	"claudecompanion/internal/notifier"
+)
+
+// hookSink passes notifications to the hook runner
+type hookSink struct {
+	runner *hooks.Runner
+}
+
+// Name returns the sink name used in logs
+func (s hookSink) Name() string {
+	return "hooks"
+}
+
+// Send starts the hooks of the notification type without waiting for them
+func (s hookSink) Send(event notifier.Event) error {
+	s.runner.Fire(hooks.Event{
+		Type:     event.Type,
+		Severity: event.Severity.String(),
+		Title:    event.Title,
+		Message:  event.Message,
+		Window:   event.Window,
+		Time:     event.Time,
+	})
+	return nil
+}
+
+// applyHooksConfig replaces the hooks with the enabled ones of cfg
+func (a *App) applyHooksConfig(cfg config.Config) {
+	var list []hooks.Hook
+	for _, h := range cfg.Hooks.Commands {
+		if h.Disabled {
+			continue
+		}
+		if h.Command == "" {
+			logger.Warning("Hook %s has no command, skipped", h.Name)
+			continue
+		}
+		name := h.Name
+		if name == "" {
+			name = h.Command
+		}
+		list = append(list, hooks.Hook{
+			Name:    name,
+			Events:  h.Events,
+			Command: h.Command,
+			Dir:     h.Dir,
+			Timeout: time.Duration(h.TimeoutSeconds) * time.Second,
+		})
+		logger.Info("Hook enabled: %s on %v", name, h.Events)
+	}
+	a.hooks.SetHooks(list, cfg.Hooks.MaxConcurrent)
+}
@@ -2,14 +2,28 @@ package main
 
 import (
 	_ "embed"
+	"flag"
+	"fmt"
 	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"claudecompanion/internal/api"
 	"claudecompanion/internal/config"
+	"claudecompanion/internal/forecast"
+	"claudecompanion/internal/history"
+	"claudecompanion/internal/hooks"
 	"claudecompanion/internal/logger"
+	"claudecompanion/internal/metrics"
+	"claudecompanion/internal/mqtt"
 	"claudecompanion/internal/notifier"
 	"claudecompanion/internal/server"
+	"claudecompanion/internal/state"
 	"claudecompanion/internal/tray"
 
 	"github.com/getlantern/systray"
@@ -24,18 +38,50 @@ type App struct {
 	configMgr         *config.Manager
 	apiClient         *api.Client
 	httpServer        *server.Server
-	trayMgr           *tray.TrayManager
+	usageState        *state.Store
+	historyStore      *history.Store // nil when history is disabled
+	historyMu         sync.Mutex
+	mqttPublisher     *mqtt.Publisher // nil when MQTT is disabled
+	mqttSettings      mqtt.Settings
+	mqttMu            sync.Mutex
+	display           Display // Tray icon, or the log in headless mode
 	notifier          *notifier.Notifier
+	desktopSink       *notifier.DesktopSink
+	deadLetter        *notifier.DeadLetter // Webhook deliveries that kept failing
+	hooks             *hooks.Runner
 	cronScheduler     *cron.Cron
 	errorCount        int
+	lastError         *api.FetchError
 	lastValue         int
+	pollMu            sync.Mutex // Serializes automatic and manual polls
+	scheduleMu        sync.Mutex
+	nextPollAt        time.Time
+	trend             usageTrend // Primary window changes, drives adaptive polling
+	estimator         *forecast.Estimator
+	ladder            *notifier.Ladder        // Notified low quota levels per window
+	lastWindows       map[string]windowSample // Windows of the previous successful poll, to detect resets
+	forecastWindow    string                  // Window and period the forecast notification was shown for
+	forecastResetsAt  *time.Time
+	rescheduleChan    chan struct{}
 	stopChan          chan struct{}
 	demoMode          bool
 	demoStarted       time.Time
 	demoGreetingShown bool
+	headless          bool        // Run without the system tray
+	paused            atomic.Bool // Automatic polling paused from the command line
+	shutdownOnce      sync.Once
 }
 
 func main() {
+	// Subcommands talk to a running instance and don't start the app
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	headless := flag.Bool("headless", false, "Run without the system tray, stop on SIGINT/SIGTERM")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, cliUsage) }
+	flag.Parse()
+
 	// Initialize logger first - THIS IS CRITICAL
 	if err := logger.Init(); err != nil {
 		panic("FATAL: Failed to initialize logger: " + err.Error())
@@ -57,8 +103,11 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 
 	app := &App{
-		stopChan:  make(chan struct{}),
-		lastValue: -1,
+		stopChan:       make(chan struct{}),
+		rescheduleChan: make(chan struct{}, 1),
+		usageState:     state.NewStore(),
+		lastValue:      -1,
+		headless:       *headless,
 	}
 
 	// Initialize configuration
@@ -72,6 +121,7 @@ func main() {
 	logger.Info("Configuration loaded successfully from: %s", cfgMgr.GetPath())
 
 	cfg := cfgMgr.Get()
+	app.estimator = forecast.NewEstimator(forecastLookback(cfg))
 
 	// Setup file logging based on config
 	if err := logger.SetFileLogging(cfg.EnableFileLogging); err != nil {
@@ -89,40 +139,87 @@ func main() {
 		logger.Warning("===========================================")
 		app.demoMode = true
 		app.demoStarted = time.Now()
+		app.usageState.SetDemo(true)
 	}
 
 	// Initialize components
 	logger.Info("Initializing components...")
 
-	logger.Info("  - API client (proxy: %s, curl: %s, full logging: %v)...", cfg.Proxy, cfg.CurlPath, cfg.EnableFileFullLogging)
-	app.apiClient = api.NewClient(cfg.Proxy, cfg.CurlPath, cfg.EnableFileFullLogging)
-	logger.Info("  - API client initialized")
+	logger.Info("  - API client (transport: %s, proxy: %s, curl: %s, full logging: %v)...", cfg.Transport, cfg.Proxy, cfg.CurlPath, cfg.EnableFileFullLogging)
+	app.apiClient = api.NewClient(apiSettings(cfg))
+	logger.Info("  - API client initialized (transport: %s)", app.apiClient.TransportName())
+
+	// Demo values are not written to history
+	if !app.demoMode {
+		logger.Info("  - History...")
+		app.applyHistoryConfig(cfg)
+	}
+
+	logger.Info("  - Pairing token...")
+	pairingToken, err := server.LoadOrCreatePairingToken(filepath.Join(filepath.Dir(cfgMgr.GetPath()), server.PairingTokenFile))
+	if err != nil {
+		logger.Fatal("Failed to load pairing token: %v", err)
+		return
+	}
 
 	logger.Info("  - HTTP server on port %d...", cfg.ServerPort)
 	app.httpServer = server.NewServer(cfg.ServerPort)
+	app.httpServer.SetPairing(pairingToken, !cfg.Pairing.Disabled, cfg.Pairing.AllowedHosts)
+	app.httpServer.SetStateStore(app.usageState)
+	app.httpServer.SetControlHandler(app.handleControl)
+	if cfg.Pairing.Disabled {
+		logger.Warning("  - Pairing is DISABLED: any local process can send context")
+	}
 	logger.Info("  - HTTP server initialized")
 
-	logger.Info("  - System tray manager...")
-	app.trayMgr = tray.NewTrayManager(cfgMgr.GetPath(), &cfg.IconColors)
-	app.trayMgr.SetBrowserPath(cfg.BrowserPath)
-	logger.Info("  - Tray manager initialized")
+	var trayMgr *tray.TrayManager
+	if app.headless {
+		logger.Info("  - Headless mode: system tray disabled, status goes to the log")
+		app.display = &headlessDisplay{}
+	} else {
+		logger.Info("  - System tray manager...")
+		trayMgr = tray.NewTrayManager(cfgMgr.GetPath(), &cfg.IconColors)
+		trayMgr.SetBrowserPath(cfg.BrowserPath)
+		trayMgr.SetPairingToken(pairingToken)
+		trayMgr.SetDashboardURL(fmt.Sprintf("http://127.0.0.1:%d/", cfg.ServerPort))
+		app.display = trayMgr
+		logger.Info("  - Tray manager initialized")
+	}
 
 	logger.Info("  - Notifier...")
-	app.notifier = notifier.NewNotifier(embeddedIcon)
+	app.notifier = notifier.NewNotifier()
+	app.desktopSink = notifier.NewDesktopSink(embeddedIcon)
+	app.deadLetter = notifier.NewDeadLetter(filepath.Join(filepath.Dir(cfgMgr.GetPath()), notifier.DeadLetterFile))
+	app.hooks = hooks.NewRunner()
+	app.applyHooksConfig(cfg)
+	app.applySinkConfig(cfg)
+	// Demo values aren't persisted, so the real ladder state survives a demo session
+	if app.demoMode {
+		app.ladder = notifier.NewLadder("")
+	} else {
+		app.ladder = notifier.NewLadder(filepath.Join(filepath.Dir(cfgMgr.GetPath()), notifier.LadderStateFile))
+	}
 	logger.Info("  - Notifier initialized")
 
 	// Set callbacks
 	logger.Info("Setting up callbacks...")
-	app.trayMgr.SetExitCallback(func() {
-		logger.Info("Exit callback triggered by user")
-		app.Shutdown()
-	})
-
-	// Set refresh callback for manual statistics update
-	app.trayMgr.SetRefreshCallback(func() {
-		logger.Info("Manual refresh requested by user")
-		app.pollManual()
-	})
+	app.setupMetrics()
+
+	// Forward notifications to /events subscribers
+	notifier.AddListener(app.usageState.PublishNotification)
+
+	if trayMgr != nil {
+		trayMgr.SetExitCallback(func() {
+			logger.Info("Exit callback triggered by user")
+			app.Shutdown()
+		})
+
+		// Set refresh callback for manual statistics update
+		trayMgr.SetRefreshCallback(func() {
+			logger.Info("Manual refresh requested by user")
+			app.pollManual()
+		})
+	}
 
 	// Don't set OpenSettings callback - use default implementation from tray.go
 	// which opens the file in notepad.exe on Windows
@@ -138,11 +235,17 @@ func main() {
 			logger.Info("    User-Agent: %s", ua)
 		}
 		app.apiClient.SetContext(cookies, targetURL, organizationID, headers)
-		app.trayMgr.UpdateTargetURL(targetURL)
+		app.display.UpdateTargetURL(targetURL)
 		// Reset error count when new cookies arrive
 		app.errorCount = 0
+		app.usageState.SetContextReceived(time.Now())
 		app.notifier.ResetAll()
+		app.ladder.SetAccount(organizationID)
 		logger.Info("    Context updated successfully, error count reset")
+		app.hooks.Fire(hooks.Event{
+			Type: hooks.EventContext,
+			Data: map[string]string{"target_url": targetURL, "organization_id": organizationID},
+		})
 
 		// Setup greeting scheduler when context is received
 		app.setupGreetingScheduler()
@@ -158,15 +261,35 @@ func main() {
 		}
 
 		// Update browser path
-		app.trayMgr.SetBrowserPath(newCfg.BrowserPath)
+		app.display.SetBrowserPath(newCfg.BrowserPath)
 		logger.Info("    Browser path updated: %s", newCfg.BrowserPath)
 
+		// Update pairing settings (token itself is stored separately and doesn't change)
+		app.httpServer.SetPairing(pairingToken, !newCfg.Pairing.Disabled, newCfg.Pairing.AllowedHosts)
+		logger.Info("    Pairing settings updated: required=%v, allowed hosts=%v", !newCfg.Pairing.Disabled, newCfg.Pairing.AllowedHosts)
+
 		// Update API client settings (preserves cookies and context)
 		logger.Info("    Updating API client settings (cookies preserved)...")
-		app.apiClient.UpdateSettings(newCfg.Proxy, newCfg.CurlPath, newCfg.EnableFileFullLogging)
+		app.apiClient.UpdateSettings(apiSettings(*newCfg))
 		logger.Info("    API client settings updated successfully")
+
+		// Rebuild hooks and notification sinks
+		app.applyHooksConfig(*newCfg)
+		app.applySinkConfig(*newCfg)
+
+		// Open, close or update history and reconnect MQTT
+		if !app.demoMode {
+			app.applyHistoryConfig(*newCfg)
+			app.applyMQTTConfig(*newCfg)
+		}
 	})
 
+	// Publish state to MQTT (demo values would overwrite retained topics)
+	if !app.demoMode && cfg.MQTT.Enabled {
+		logger.Info("Starting MQTT publisher for %s ...", cfg.MQTT.Broker)
+		app.applyMQTTConfig(cfg)
+	}
+
 	// Start HTTP server (unless in demo mode)
 	if !app.demoMode {
 		logger.Info("Starting HTTP server on http://127.0.0.1:%d ...", cfg.ServerPort)
@@ -177,17 +300,41 @@ func main() {
 		logger.Info("HTTP server started successfully")
 		logger.Info("  - Endpoint: POST http://127.0.0.1:%d/set-context", cfg.ServerPort)
 		logger.Info("  - Health: GET http://127.0.0.1:%d/health", cfg.ServerPort)
+		logger.Info("  - Dashboard: http://127.0.0.1:%d/", cfg.ServerPort)
+		logger.Info("  - Usage: GET http://127.0.0.1:%d/usage", cfg.ServerPort)
+		logger.Info("  - Events: GET http://127.0.0.1:%d/events", cfg.ServerPort)
+		logger.Info("  - History: GET http://127.0.0.1:%d/history", cfg.ServerPort)
+		logger.Info("  - Metrics: GET http://127.0.0.1:%d/metrics", cfg.ServerPort)
+		logger.Info("  - Control: POST http://127.0.0.1:%d/control/<action>", cfg.ServerPort)
 	} else {
 		logger.Info("Demo mode: HTTP server NOT started")
 	}
 
+	// Stop gracefully on Ctrl+C and service stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	if app.headless {
+		app.startLoops(cfg)
+		sig := <-signals
+		logger.Info("Received %s, stopping...", sig)
+		app.Shutdown()
+		return
+	}
+
+	go func() {
+		sig := <-signals
+		logger.Info("Received %s, closing tray...", sig)
+		systray.Quit()
+	}()
+
 	// Run systray
 	logger.Info("===========================================")
 	logger.Info("Initializing system tray...")
 	logger.Info("===========================================")
 	systray.Run(func() {
 		logger.Info(">>> Systray ready, initializing tray manager...")
-		app.trayMgr.Initialize()
+		trayMgr.Initialize()
 		logger.Info(">>> Tray manager initialized successfully")
 
 		// Wait a bit for systray to fully initialize
@@ -195,17 +342,7 @@ func main() {
 		time.Sleep(500 * time.Millisecond)
 
 		// Start polling AFTER systray is ready
-		if app.demoMode {
-			logger.Info("Starting DEMO mode loop (interval: 2 seconds)...")
-			go app.demoLoop()
-		} else {
-			logger.Info("Starting API poll loop (interval: %d seconds)...", cfg.PollIntervalSeconds)
-			go app.pollLoop()
-		}
-
-		logger.Info("===========================================")
-		logger.Info("Application startup complete!")
-		logger.Info("===========================================")
+		app.startLoops(cfg)
 	}, func() {
 		logger.Info("Systray exit callback triggered")
 		app.Shutdown()
@@ -214,6 +351,32 @@ func main() {
 	logger.Info("Main function completed, application should now be running in tray")
 }
 
+// startLoops starts polling (or the demo loop)
+func (a *App) startLoops(cfg config.Config) {
+	if a.demoMode {
+		logger.Info("Starting DEMO mode loop (interval: 2 seconds)...")
+		go a.demoLoop()
+	} else {
+		logger.Info("Starting API poll loop (interval: %d seconds)...", cfg.PollIntervalSeconds)
+		go a.pollLoop()
+	}
+
+	logger.Info("===========================================")
+	logger.Info("Application startup complete!")
+	logger.Info("===========================================")
+}
+
+// apiSettings converts config values into API client settings
+func apiSettings(cfg config.Config) api.Settings {
+	return api.Settings{
+		Transport:   cfg.Transport,
+		Proxy:       cfg.Proxy,
+		CurlPath:    cfg.CurlPath,
+		Timeout:     time.Duration(cfg.RequestTimeoutSeconds) * time.Second,
+		FullLogging: cfg.EnableFileFullLogging,
+	}
+}
+
 // demoLoop runs demo mode with 2 second interval
 func (a *App) demoLoop() {
 	logger.Info("Demo loop started")
@@ -235,33 +398,37 @@ func (a *App) demoLoop() {
 	}
 }
 
-// pollLoop polls the API periodically
+// pollLoop polls the API on the schedule computed after each poll
+// (normal interval with jitter, exponential backoff on errors, Retry-After on 429)
 func (a *App) pollLoop() {
 	logger.Info("Poll loop started")
-	cfg := a.configMgr.Get()
-	ticker := time.NewTicker(time.Duration(cfg.PollIntervalSeconds) * time.Second)
-	defer ticker.Stop()
 
 	// Poll immediately on start
 	logger.Info("Performing initial poll...")
 	a.poll()
 
+	timer := time.NewTimer(time.Until(a.getNextPollAt()))
+	defer timer.Stop()
+
 	for {
 		select {
-		case <-ticker.C:
-			logger.Debug("Poll tick (interval: %ds)", cfg.PollIntervalSeconds)
+		case <-timer.C:
+			logger.Debug("Poll timer fired")
 			a.poll()
-			// Update ticker interval if config changed
-			newCfg := a.configMgr.Get()
-			if newCfg.PollIntervalSeconds != cfg.PollIntervalSeconds {
-				logger.Info("Poll interval changed: %ds -> %ds", cfg.PollIntervalSeconds, newCfg.PollIntervalSeconds)
-				cfg = newCfg
-				ticker.Reset(time.Duration(cfg.PollIntervalSeconds) * time.Second)
-			}
+		case <-a.rescheduleChan:
+			// Schedule changed (manual refresh), re-arm timer below
 		case <-a.stopChan:
 			logger.Info("Poll loop stopped")
 			return
 		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(a.getNextPollAt()))
 	}
 }
 
@@ -275,20 +442,32 @@ func (a *App) pollManual() {
 	a.doPoll(true)
 }
 
-// doPoll performs a single API poll
+// doPoll performs a single API poll and schedules the next automatic one
 func (a *App) doPoll(isManual bool) {
+	a.pollMu.Lock()
+	defer a.pollMu.Unlock()
+
 	cfg := a.configMgr.Get()
 
 	// Check if we have context
 	if !a.apiClient.HasContext() {
 		logger.Debug("No cookies received yet from extension")
 		a.updateTrayNoCookies()
+		a.scheduleNextPoll(cfg)
+		return
+	}
+
+	// Paused from the command line (manual requests still poll)
+	if !isManual && a.paused.Load() {
+		logger.Debug("Polling paused, skipping automatic poll")
+		a.scheduleNextPoll(cfg)
 		return
 	}
 
 	// Check work hours (skip check if manual request)
 	if !isManual && !cfg.WorkHours.IsWithinWorkHours() {
 		logger.Debug("Outside work hours, skipping automatic poll")
+		a.scheduleNextPoll(cfg)
 		return
 	}
 
@@ -299,11 +478,19 @@ func (a *App) doPoll(isManual bool) {
 		logger.Debug("Automatic poll: Fetching usage from API...")
 	}
 
+	metrics.PollAttempts.Inc()
 	usage, err := a.apiClient.GetUsage()
 	if err != nil {
-		logger.Error("API request failed (error #%d): %v", a.errorCount+1, err)
+		fetchErr := api.ClassifyError(err)
+		metrics.PollFailures.Inc(fetchErr.Kind.String())
+		logger.Error("API request failed (error #%d, %s): %v", a.errorCount+1, fetchErr.Kind, err)
 		a.errorCount++
-		a.handleError(cfg)
+		a.lastError = fetchErr
+		a.usageState.SetError(fetchErr, a.errorCount, time.Now())
+		a.recordError(fetchErr, time.Now())
+		metrics.ConsecutiveErrors.Set(float64(a.errorCount))
+		delay := a.scheduleNextPoll(cfg)
+		a.handleError(cfg, fetchErr, delay)
 		return
 	}
 
@@ -312,73 +499,103 @@ func (a *App) doPoll(isManual bool) {
 		logger.Info("API request succeeded after %d errors", a.errorCount)
 	}
 	a.errorCount = 0
+	a.lastError = nil
 	a.notifier.ResetErrorNotification()
+	metrics.PollSuccesses.Inc()
+	metrics.ConsecutiveErrors.Set(0)
 
-	// Get inverted value (remaining quota)
+	// Get inverted value (remaining quota) of the configured window
+	usage.SelectWindow(cfg.UsageWindow)
 	value := usage.GetInvertedValue()
 	tooltip := usage.FormatTooltip()
+	primaryForecast := a.updateForecast(cfg, usage, time.Now())
+	if primaryForecast != nil && primaryForecast.BeforeReset && value > 0 {
+		tooltip += "\r\n" + primaryForecast.TooltipLine()
+	}
+	a.usageState.SetUsage(usage, time.Now())
+	a.recordUsage(usage, time.Now())
+	updateWindowMetrics(usage)
+
+	// Update trend and schedule next poll (sleeps until reset when exhausted)
+	a.trend.update(usage.PrimaryWindow(), time.Now())
+	a.scheduleNextPoll(cfg)
+	if *cfg.Scheduler.Adaptive && a.trend.exhaustedUntil(time.Now()) != nil {
+		tooltip += "\r\nСледующий опрос в " + a.getNextPollAt().Format("15:04")
+	}
 
 	logger.Debug("API response: remaining=%d%%, tooltip=%s", value, tooltip)
 
 	// Update tray
-	a.trayMgr.UpdateIcon(value, false, tooltip)
+	a.display.UpdateIcon(value, false, tooltip)
 	a.lastValue = value
 
-	// Check for low value notifications
-	a.checkLowValueNotifications(value, cfg, usage)
+	// Check for restored, low value and forecast notifications
+	a.checkRestoredNotifications(cfg, usage)
+	a.checkLowValueNotifications(cfg, usage)
+	a.checkForecastNotification(cfg, usage, primaryForecast)
 }
 
 // handleError handles API errors
-func (a *App) handleError(cfg config.Config) {
-	// Show gray icon after threshold
-	if a.errorCount >= cfg.GrayModeThreshold {
-		tooltip := "Ошибка подключения к API"
-		logger.Warning("Error count (%d) reached gray mode threshold (%d)", a.errorCount, cfg.GrayModeThreshold)
-		a.trayMgr.UpdateIcon(a.lastValue, true, tooltip)
+// Errors that need user action (expired session, Cloudflare check) are reported on the first failure
+func (a *App) handleError(cfg config.Config, fetchErr *api.FetchError, nextAttempt time.Duration) {
+	grayThreshold := cfg.GrayModeThreshold
+	notifyThreshold := cfg.NotificationThreshold
+	if fetchErr.Kind.NeedsUserAction() {
+		grayThreshold = 1
+		notifyThreshold = 1
+	}
+
+	// Show gray icon after threshold, otherwise keep last value and only mention the retry
+	if a.errorCount >= grayThreshold {
+		tooltip := fetchErr.Description() + "\r\n" + fetchErr.Hint() + "\r\n" + formatNextAttempt(nextAttempt)
+		logger.Warning("Error count (%d) reached gray mode threshold (%d), kind: %s", a.errorCount, grayThreshold, fetchErr.Kind)
+		a.display.UpdateIcon(a.lastValue, true, tooltip)
+	} else {
+		tooltip := fetchErr.Description() + "\r\n" + formatNextAttempt(nextAttempt)
+		a.display.UpdateIcon(a.lastValue, false, tooltip)
 	}
 
 	// Show notification after threshold
-	if a.errorCount >= cfg.NotificationThreshold {
-		logger.Warning("Error count (%d) reached notification threshold (%d)", a.errorCount, cfg.NotificationThreshold)
-		a.notifier.NotifyError(a.errorCount, cfg.NotificationThreshold)
+	if a.errorCount >= notifyThreshold {
+		logger.Warning("Error count (%d) reached notification threshold (%d), kind: %s", a.errorCount, notifyThreshold, fetchErr.Kind)
+		message := fetchErr.Description() + ". " + fetchErr.Hint()
+		a.notifier.NotifyError(a.errorCount, notifyThreshold, fetchErr.Title(), message)
 	}
 }
 
-// checkLowValueNotifications checks if we should show low value notifications
-func (a *App) checkLowValueNotifications(value int, cfg config.Config, usage *api.UsageResponse) {
-	if !cfg.LowValueNotifications.Enabled {
-		return
-	}
+// checkLowValueNotifications steps every window through its notification ladder
+// Each window has its own state, so the weekly limit is reported independently
+func (a *App) checkLowValueNotifications(cfg config.Config, usage *api.UsageResponse) {
+	primary := usage.PrimaryWindow()
+
+	for i := range usage.Windows {
+		w := &usage.Windows[i]
+		isPrimary := primary != nil && primary.Name == w.Name
+		settings, enabled := cfg.LowValueNotifications.ForWindow(w.Name, isPrimary)
+		if !enabled {
+			continue
+		}
 
-	// Check for zero value (show notification once)
-	if value == 0 {
-		phrase := config.GetRandomPhrase(cfg.LowValueNotifications.ZeroPhrases)
-		var resetTime string
-		if usage.FiveHour.ResetsAt != nil {
-			resetTime = usage.FiveHour.ResetsAt.Local().Format("15:04:05")
-		} else {
-			resetTime = "—"
+		value := w.Remaining()
+		level := a.ladder.Step(w.Name, value, w.ResetsAt, settings.Ladder(), *settings.Hysteresis)
+		if level == nil {
+			continue
 		}
-		logger.Warning("Quota reached ZERO, showing notification")
-		a.notifier.NotifyZero(phrase, resetTime)
-		return
-	}
 
-	// Check for low value (show notification once when dropping below threshold)
-	if value <= cfg.LowValueNotifications.Threshold {
-		phrase := config.GetRandomPhrase(cfg.LowValueNotifications.Phrases)
-		logger.Warning("Quota low (%d%% <= %d%%), showing notification", value, cfg.LowValueNotifications.Threshold)
-		a.notifier.NotifyLowValue(value, phrase)
-	} else {
-		// Reset notification state when value goes above threshold
-		// This also resets zero notification state
-		a.notifier.ResetLowValueNotification()
+		// The five-hour window keeps the short title, others are named
+		label := ""
+		if w.Name != api.WindowFiveHour {
+			label = w.Label()
+		}
+		phrase := config.GetRandomPhrase(level.Phrases)
+		logger.Warning("Quota of %s reached level %d%% (remaining %d%%), showing notification", w.Name, level.Threshold, value)
+		a.notifier.NotifyLevel(label, *level, phrase, api.FormatResetTime(w.ResetsAt))
 	}
 }
 
 // updateTrayNoCookies updates the tray when no cookies are available
 func (a *App) updateTrayNoCookies() {
-	a.trayMgr.UpdateIcon(-1, false, "Ожидаю куки от расширения")
+	a.display.UpdateIcon(-1, false, "Ожидаю куки от расширения")
 }
 
 // handleDemoMode simulates declining values in demo mode (infinite loop)
@@ -411,16 +628,19 @@ func (a *App) handleDemoMode(cfg config.Config) {
 
 	// Create fake usage response for demo mode
 	utilization := float64(100 - value)
-	fakeUsage := &api.UsageResponse{}
-	fakeUsage.FiveHour.Utilization = utilization
 	fiveHourReset := time.Now().Add(time.Hour * 2)
-	fakeUsage.FiveHour.ResetsAt = &fiveHourReset
-	fakeUsage.SevenDay.Utilization = utilization / 2 // Half for weekly
 	sevenDayReset := time.Now().Add(time.Hour * 24 * 7)
-	fakeUsage.SevenDay.ResetsAt = &sevenDayReset
+	fakeUsage := &api.UsageResponse{
+		Windows: []api.UsageWindow{
+			{Name: api.WindowFiveHour, Utilization: utilization, ResetsAt: &fiveHourReset},
+			{Name: api.WindowSevenDay, Utilization: utilization / 2, ResetsAt: &sevenDayReset}, // Half for weekly
+		},
+	}
+	fakeUsage.SelectWindow(cfg.UsageWindow)
+	a.usageState.SetUsage(fakeUsage, time.Now())
 
 	tooltip := fakeUsage.FormatTooltip()
-	a.trayMgr.UpdateIcon(value, false, tooltip)
+	a.display.UpdateIcon(value, false, tooltip)
 	a.lastValue = value
 
 	// Reset greeting notification flag at the start of each cycle
@@ -438,7 +658,8 @@ func (a *App) handleDemoMode(cfg config.Config) {
 
 	// Trigger notifications in demo mode
 	// checkLowValueNotifications handles reset when value goes above threshold
-	a.checkLowValueNotifications(value, cfg, fakeUsage)
+	a.checkRestoredNotifications(cfg, fakeUsage)
+	a.checkLowValueNotifications(cfg, fakeUsage)
 
 	// No error simulation in demo mode - let it run clean
 	a.errorCount = 0
@@ -505,7 +726,13 @@ func (a *App) sendGreeting() {
 }
 
 // Shutdown performs cleanup before exit
+// Safe to call more than once (tray menu, systray exit and signals may all trigger it)
 func (a *App) Shutdown() {
+	a.shutdownOnce.Do(a.shutdown)
+}
+
+// shutdown stops all components
+func (a *App) shutdown() {
 	logger.Info("===========================================")
 	logger.Info("Shutting down ClaudeCompanion...")
 	logger.Info("===========================================")
@@ -520,6 +747,8 @@ func (a *App) Shutdown() {
 		a.httpServer.Stop()
 		logger.Info("HTTP server stopped")
 	}
+	a.stopMQTT()
+	a.closeHistory()
 	logger.Info("Shutdown complete. Goodbye!")
 	logger.Info("===========================================")
 }
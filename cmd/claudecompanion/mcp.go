@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"claudecompanion/internal/mcp"
+	"claudecompanion/internal/state"
+)
+
+// cmdMCP serves MCP (Model Context Protocol) over stdio for agents
+// Usage is read from the running instance on every call; stdout carries only protocol messages
+func cmdMCP(args []string) int {
+	var opts cliOptions
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.IntVar(&opts.port, "port", 0, "Порт локального API (по умолчанию server_port из config.yaml)")
+	fs.DurationVar(&opts.timeout, "timeout", 5*time.Second, "Сколько ждать ответа приложения")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return exitUsage
+	}
+
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	srv := mcp.NewServer(func() (*state.Snapshot, error) {
+		snap, err := c.Usage()
+		if err != nil {
+			return nil, fmt.Errorf("ClaudeCompanion is not available on port %d: %v", opts.port, err)
+		}
+		return snap, nil
+	})
+	if err := srv.Serve(os.Stdin, os.Stdout); err != nil {
+		return opts.fail(err)
+	}
+	return exitOK
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/metrics"
+	"claudecompanion/internal/notifier"
+)
+
+// setupMetrics wires request latency, notification counters and scrape-time gauges
+func (a *App) setupMetrics() {
+	a.apiClient.SetRequestHook(func(transport, kind string, duration time.Duration) {
+		metrics.RequestDuration.Observe(duration.Seconds(), transport, kind)
+	})
+
+	notifier.AddListener(func(notificationType, title, message string) {
+		metrics.Notifications.Inc(notificationType)
+	})
+
+	// Time-dependent gauges are computed from the shared state on every scrape
+	metrics.OnScrape(func() {
+		snap := a.usageState.Snapshot()
+		now := time.Now()
+
+		metrics.WindowResetSeconds.Replace(func(set func(float64, ...string)) {
+			for _, w := range snap.Windows {
+				if w.ResetsAt != nil {
+					set(w.ResetsAt.Sub(now).Seconds(), w.Name)
+				}
+			}
+		})
+
+		if snap.ContextReceivedAt != nil {
+			metrics.ContextAge.Set(now.Sub(*snap.ContextReceivedAt).Seconds())
+		} else {
+			metrics.ContextAge.Set(-1)
+		}
+	})
+}
+
+// updateWindowMetrics exports utilization of every window from a successful poll
+func updateWindowMetrics(usage *api.UsageResponse) {
+	metrics.WindowUtilization.Replace(func(set func(float64, ...string)) {
+		for i := range usage.Windows {
+			set(usage.Windows[i].Utilization, usage.Windows[i].Name)
+		}
+	})
+	metrics.WindowRemaining.Replace(func(set func(float64, ...string)) {
+		for i := range usage.Windows {
+			set(float64(usage.Windows[i].Remaining()), usage.Windows[i].Name)
+		}
+	})
+}
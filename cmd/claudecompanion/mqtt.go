@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/logger"
+	"claudecompanion/internal/mqtt"
+)
+
+// mqttSettings converts the mqtt section of the config to publisher settings
+func mqttSettings(cfg config.MQTT) mqtt.Settings {
+	return mqtt.Settings{
+		Broker:          cfg.Broker,
+		ClientID:        cfg.ClientID,
+		Username:        cfg.Username,
+		Password:        cfg.Password,
+		TopicPrefix:     cfg.TopicPrefix,
+		Discovery:       !cfg.Discovery.Disabled,
+		DiscoveryPrefix: cfg.Discovery.Prefix,
+		TLS: mqtt.TLSSettings{
+			CAFile:             cfg.TLS.CAFile,
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		},
+	}
+}
+
+// applyMQTTConfig starts, stops or reconnects the MQTT publisher according to cfg
+func (a *App) applyMQTTConfig(cfg config.Config) {
+	a.mqttMu.Lock()
+	defer a.mqttMu.Unlock()
+
+	settings := mqttSettings(cfg.MQTT)
+	if a.mqttPublisher != nil {
+		if cfg.MQTT.Enabled && reflect.DeepEqual(settings, a.mqttSettings) {
+			return
+		}
+		a.mqttPublisher.Stop()
+		a.mqttPublisher = nil
+	}
+	if !cfg.MQTT.Enabled {
+		return
+	}
+
+	publisher, err := mqtt.NewPublisher(settings, a.usageState)
+	if err != nil {
+		logger.Warning("MQTT publisher disabled: %v", err)
+		return
+	}
+	publisher.Start()
+	a.mqttPublisher = publisher
+	a.mqttSettings = settings
+}
+
+// stopMQTT disconnects the MQTT publisher
+func (a *App) stopMQTT() {
+	a.mqttMu.Lock()
+	defer a.mqttMu.Unlock()
+	if a.mqttPublisher != nil {
+		a.mqttPublisher.Stop()
+		a.mqttPublisher = nil
+	}
+}
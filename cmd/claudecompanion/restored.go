@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/logger"
+)
+
+// windowSample is the last seen state of a window, used to detect resets
+type windowSample struct {
+	utilization float64
+	resetsAt    *time.Time
+}
+
+// windowReset returns true if the window started a new period since prev:
+// ResetsAt moved or utilization dropped
+func windowReset(prev windowSample, w *api.UsageWindow) bool {
+	return api.ResetMoved(prev.resetsAt, w.ResetsAt) || w.Utilization < prev.utilization-0.5
+}
+
+// checkRestoredNotifications detects window resets and tells the user quota is back
+// Must run before checkLowValueNotifications, which forgets the levels of the finished period
+func (a *App) checkRestoredNotifications(cfg config.Config, usage *api.UsageResponse) {
+	previous := a.lastWindows
+	a.lastWindows = make(map[string]windowSample, len(usage.Windows))
+	for _, w := range usage.Windows {
+		a.lastWindows[w.Name] = windowSample{utilization: w.Utilization, resetsAt: w.ResetsAt}
+	}
+
+	rn := cfg.RestoredNotifications
+	if !rn.Enabled || previous == nil {
+		return
+	}
+
+	primary := usage.PrimaryWindow()
+	for i := range usage.Windows {
+		w := &usage.Windows[i]
+		prev, ok := previous[w.Name]
+		if !ok || !windowReset(prev, w) {
+			continue
+		}
+		logger.Info("Window %s reset: %.0f%% -> %.0f%%", w.Name, prev.utilization, w.Utilization)
+
+		// Only windows that are watched for low quota are reported
+		isPrimary := primary != nil && primary.Name == w.Name
+		if _, enabled := cfg.LowValueNotifications.ForWindow(w.Name, isPrimary); !enabled {
+			continue
+		}
+
+		threshold, notified := a.ladder.Level(w.Name)
+		switch rn.OnlyAfter {
+		case "zero":
+			if !notified || threshold > 0 {
+				continue
+			}
+		case "low":
+			if !notified {
+				continue
+			}
+		}
+
+		label := ""
+		if w.Name != api.WindowFiveHour {
+			label = w.Label()
+		}
+		a.notifier.NotifyRestored(label, config.GetRandomPhrase(rn.Phrases))
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/logger"
+)
+
+// usageTrend tracks how the primary window utilization changes between successful polls
+type usageTrend struct {
+	window      string
+	utilization float64
+	remaining   int
+	resetsAt    *time.Time
+	sampledAt   time.Time
+	changedAt   time.Time // Last time utilization changed
+	lastDelta   float64   // Absolute utilization change between the last two polls
+}
+
+// update records a new sample of the primary window
+func (t *usageTrend) update(w *api.UsageWindow, now time.Time) {
+	if w == nil {
+		return
+	}
+
+	if t.sampledAt.IsZero() || t.window != w.Name {
+		t.lastDelta = 0
+		t.changedAt = now
+	} else {
+		delta := w.Utilization - t.utilization
+		if delta < 0 {
+			delta = -delta
+		}
+		t.lastDelta = delta
+		if delta > 0 {
+			t.changedAt = now
+		}
+	}
+
+	t.window = w.Name
+	t.utilization = w.Utilization
+	t.remaining = w.Remaining()
+	t.resetsAt = w.ResetsAt
+	t.sampledAt = now
+}
+
+// exhaustedUntil returns the reset time if the quota is exhausted and the reset is in the future
+func (t *usageTrend) exhaustedUntil(now time.Time) *time.Time {
+	if t.sampledAt.IsZero() || t.remaining > 0 || t.resetsAt == nil || !t.resetsAt.After(now) {
+		return nil
+	}
+	return t.resetsAt
+}
+
+// nextPollDelay returns the delay before the next automatic poll
+//   - no errors: poll interval (adaptive if enabled) randomized by ±jitter_percent
+//   - consecutive errors: interval doubled per error up to backoff_max_seconds, plus up to 50% jitter
+//   - rate limited: at least Retry-After (plus a small jitter)
+func nextPollDelay(cfg config.Config, errorCount int, lastErr *api.FetchError, trend *usageTrend) time.Duration {
+	interval := time.Duration(cfg.PollIntervalSeconds) * time.Second
+
+	if errorCount == 0 {
+		if *cfg.Scheduler.Adaptive && trend != nil {
+			now := time.Now()
+			// Nothing changes until reset - sleep until then plus a little jitter
+			if resetsAt := trend.exhaustedUntil(now); resetsAt != nil {
+				return resetsAt.Sub(now) + time.Duration(cfg.Scheduler.ResetJitterSeconds)*time.Second/2 +
+					randomDuration(time.Duration(cfg.Scheduler.ResetJitterSeconds)*time.Second/2)
+			}
+			interval = adaptiveInterval(cfg, interval, trend, now)
+		}
+		return applyJitter(interval, *cfg.Scheduler.JitterPercent)
+	}
+
+	maxDelay := time.Duration(cfg.Scheduler.BackoffMaxSeconds) * time.Second
+	if maxDelay < interval {
+		maxDelay = interval
+	}
+
+	delay := interval
+	for i := 1; i < errorCount && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	// Never go below the backoff value, only spread attempts upwards
+	delay += randomDuration(delay / 2)
+
+	if lastErr != nil && lastErr.RetryAfter > 0 {
+		retryAfter := lastErr.RetryAfter + randomDuration(lastErr.RetryAfter/10)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	return delay
+}
+
+// adaptiveInterval shortens the interval while utilization changes quickly and
+// doubles it for every flat_after_minutes without change, within min/max bounds
+// The upper bound never goes below the configured poll interval
+func adaptiveInterval(cfg config.Config, interval time.Duration, trend *usageTrend, now time.Time) time.Duration {
+	sc := cfg.Scheduler
+	minInterval := time.Duration(sc.MinIntervalSeconds) * time.Second
+	maxInterval := time.Duration(sc.MaxIntervalSeconds) * time.Second
+	if maxInterval > 0 && maxInterval < interval {
+		maxInterval = interval
+	}
+	flatAfter := time.Duration(sc.FlatAfterMinutes) * time.Minute
+
+	if !trend.sampledAt.IsZero() {
+		if trend.lastDelta >= sc.FastChangePercent {
+			interval /= 2
+		} else if flatFor := now.Sub(trend.changedAt); flatAfter > 0 && flatFor >= flatAfter {
+			for steps := int(flatFor / flatAfter); steps > 0 && interval < maxInterval; steps-- {
+				interval *= 2
+			}
+		}
+	}
+
+	if minInterval > 0 && interval < minInterval {
+		interval = minInterval
+	}
+	if maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
+
+// applyJitter randomizes d by ±percent
+func applyJitter(d time.Duration, percent int) time.Duration {
+	if percent <= 0 || d <= 0 {
+		return d
+	}
+	spread := d * time.Duration(percent) / 100
+	return d - spread + randomDuration(2*spread)
+}
+
+// randInt63n is the random source of poll jitter, replaced in tests
+var randInt63n = rand.Int63n
+
+// randomDuration returns a random duration in [0, max]
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(randInt63n(int64(max) + 1))
+}
+
+// scheduleNextPoll computes the next automatic poll time and wakes up the poll loop
+func (a *App) scheduleNextPoll(cfg config.Config) time.Duration {
+	delay := nextPollDelay(cfg, a.errorCount, a.lastError, &a.trend)
+
+	nextPollAt := time.Now().Add(delay)
+	a.scheduleMu.Lock()
+	a.nextPollAt = nextPollAt
+	a.scheduleMu.Unlock()
+	a.usageState.SetNextPoll(nextPollAt)
+
+	if a.errorCount > 0 {
+		logger.Info("Next poll in %s (backoff after %d errors)", delay.Round(time.Second), a.errorCount)
+	} else if resetsAt := a.trend.exhaustedUntil(time.Now()); resetsAt != nil && *cfg.Scheduler.Adaptive {
+		logger.Info("Quota exhausted, next poll in %s (reset at %s)", delay.Round(time.Second), resetsAt.Local().Format("15:04:05"))
+	} else {
+		logger.Debug("Next poll in %s", delay.Round(time.Second))
+	}
+
+	// Wake up poll loop so it picks up the new schedule (e.g. after manual refresh)
+	select {
+	case a.rescheduleChan <- struct{}{}:
+	default:
+	}
+
+	return delay
+}
+
+// getNextPollAt returns the time of the next automatic poll
+func (a *App) getNextPollAt() time.Time {
+	a.scheduleMu.Lock()
+	defer a.scheduleMu.Unlock()
+	return a.nextPollAt
+}
+
+// formatNextAttempt formats the retry hint shown in the tooltip
+func formatNextAttempt(delay time.Duration) string {
+	return "Следующая попытка через " + api.FormatDuration(delay)
+}
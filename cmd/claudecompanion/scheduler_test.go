@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+)
+
+// withRandom makes jitter deterministic: upper picks the upper bound of every range, otherwise 0
+func withRandom(t *testing.T, upper bool) {
+	orig := randInt63n
+	randInt63n = func(n int64) int64 {
+		if upper {
+			return n - 1
+		}
+		return 0
+	}
+	t.Cleanup(func() { randInt63n = orig })
+}
+
+func schedulerConfig(jitter int) config.Config {
+	adaptive := false
+	return config.Config{
+		PollIntervalSeconds: 60,
+		Scheduler: config.Scheduler{
+			JitterPercent:      &jitter,
+			Adaptive:           &adaptive,
+			BackoffMaxSeconds:  600,
+			MinIntervalSeconds: 30,
+			MaxIntervalSeconds: 600,
+			FastChangePercent:  2,
+			FlatAfterMinutes:   30,
+			ResetJitterSeconds: 60,
+		},
+	}
+}
+
+func TestNextPollDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		jitter     int
+		errorCount int
+		retryAfter time.Duration
+		maxRandom  bool
+		want       time.Duration
+	}{
+		{name: "no errors, lower jitter bound", jitter: 10, want: 54 * time.Second},
+		{name: "no errors, upper jitter bound", jitter: 10, maxRandom: true, want: 66 * time.Second},
+		{name: "jitter off", jitter: 0, maxRandom: true, want: 60 * time.Second},
+		{name: "first error", jitter: 10, errorCount: 1, want: 60 * time.Second},
+		{name: "doubles per error", jitter: 10, errorCount: 3, want: 240 * time.Second},
+		{name: "backoff jitter adds up to half", jitter: 10, errorCount: 3, maxRandom: true, want: 360 * time.Second},
+		{name: "capped at backoff_max_seconds", jitter: 10, errorCount: 10, want: 600 * time.Second},
+		{name: "cap with jitter", jitter: 10, errorCount: 10, maxRandom: true, want: 900 * time.Second},
+		{name: "retry-after longer than backoff", jitter: 10, errorCount: 1, retryAfter: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "retry-after with jitter", jitter: 10, errorCount: 1, retryAfter: 5 * time.Minute, maxRandom: true, want: 330 * time.Second},
+		{name: "retry-after shorter than backoff", jitter: 10, errorCount: 3, retryAfter: 10 * time.Second, want: 240 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRandom(t, tt.maxRandom)
+
+			var lastErr *api.FetchError
+			if tt.errorCount > 0 {
+				lastErr = &api.FetchError{Kind: api.ErrorRateLimited, RetryAfter: tt.retryAfter}
+			}
+			got := nextPollDelay(schedulerConfig(tt.jitter), tt.errorCount, lastErr, nil)
+			if got != tt.want {
+				t.Errorf("nextPollDelay() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextPollDelayBackoffBelowInterval(t *testing.T) {
+	withRandom(t, false)
+
+	cfg := schedulerConfig(10)
+	cfg.PollIntervalSeconds = 900
+	cfg.Scheduler.BackoffMaxSeconds = 300
+
+	// backoff_max_seconds below the poll interval never polls faster than configured
+	if got := nextPollDelay(cfg, 5, &api.FetchError{Kind: api.ErrorNetwork}, nil); got != 15*time.Minute {
+		t.Errorf("nextPollDelay() = %s, want 15m", got)
+	}
+}
+
+func TestApplyJitterBounds(t *testing.T) {
+	for _, percent := range []int{1, 10, 50} {
+		for i := 0; i < 100; i++ {
+			d := applyJitter(time.Minute, percent)
+			spread := time.Minute * time.Duration(percent) / 100
+			if d < time.Minute-spread || d > time.Minute+spread {
+				t.Fatalf("applyJitter(1m, %d) = %s, want within ±%s", percent, d, spread)
+			}
+		}
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		pollSeconds int
+		lastDelta   float64
+		flatFor     time.Duration
+		want        time.Duration
+	}{
+		{name: "steady usage keeps the interval", pollSeconds: 60, lastDelta: 1, want: time.Minute},
+		{name: "fast change halves", pollSeconds: 120, lastDelta: 5, want: time.Minute},
+		{name: "fast change stops at min_interval", pollSeconds: 40, lastDelta: 5, want: 30 * time.Second},
+		{name: "doubles per flat period", pollSeconds: 60, flatFor: 65 * time.Minute, want: 4 * time.Minute},
+		{name: "flat growth capped at max_interval", pollSeconds: 60, flatFor: 10 * time.Hour, want: 10 * time.Minute},
+		{name: "poll interval above max_interval is kept", pollSeconds: 900, lastDelta: 1, want: 15 * time.Minute},
+		{name: "poll interval above max_interval while flat", pollSeconds: 1800, flatFor: 10 * time.Hour, want: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := schedulerConfig(10)
+			cfg.PollIntervalSeconds = tt.pollSeconds
+			trend := &usageTrend{
+				window:    api.WindowFiveHour,
+				lastDelta: tt.lastDelta,
+				sampledAt: now,
+				changedAt: now.Add(-tt.flatFor),
+			}
+
+			interval := time.Duration(tt.pollSeconds) * time.Second
+			if got := adaptiveInterval(cfg, interval, trend, now); got != tt.want {
+				t.Errorf("adaptiveInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"time"
+
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/logger"
+	"claudecompanion/internal/notifier"
+)
+
+// applySinkConfig rebuilds the notification sinks according to cfg
+func (a *App) applySinkConfig(cfg config.Config) {
+	sinks := cfg.NotificationSinks
+	var routes []notifier.Route
+	if desktopEnabled(sinks.Desktop, a.headless) {
+		routes = addRoute(routes, a.desktopSink, sinks.Desktop.Filter)
+	}
+	if sinks.Telegram.Enabled {
+		if sink, err := notifier.NewTelegramSink(telegramSettings(sinks.Telegram)); err != nil {
+			logger.Warning("Telegram notification sink disabled: %v", err)
+		} else {
+			routes = addRoute(routes, sink, sinks.Telegram.Filter)
+		}
+	}
+
+	for _, webhook := range sinks.Webhooks {
+		if webhook.Disabled {
+			continue
+		}
+		if sink, err := notifier.NewWebhookSink(webhookSettings(webhook), a.deadLetter); err != nil {
+			logger.Warning("Webhook notification sink %s disabled: %v", webhook.Name, err)
+		} else {
+			routes = addRoute(routes, sink, webhook.Filter)
+		}
+	}
+
+	// Hooks choose their events themselves
+	if a.hooks.HasHooks() {
+		routes = append(routes, notifier.Route{Sink: hookSink{runner: a.hooks}})
+	}
+
+	a.notifier.SetSinks(routes)
+	if len(routes) == 0 {
+		logger.Warning("All notification sinks are disabled")
+	}
+}
+
+// desktopEnabled tells if system notifications are shown; headless setups usually have
+// no notification daemon, so there they are off unless the config turns them on
+func desktopEnabled(cfg config.DesktopSink, headless bool) bool {
+	if cfg.Disabled != nil {
+		return !*cfg.Disabled
+	}
+	return !headless
+}
+
+// addRoute appends a sink with its filter; an invalid filter lets all notifications through
+func addRoute(routes []notifier.Route, sink notifier.Sink, filter config.SinkFilter) []notifier.Route {
+	f, err := notifier.NewFilter(filter.Types, filter.MinSeverity)
+	if err != nil {
+		logger.Warning("Invalid filter of %s notification sink, all notifications are delivered: %v", sink.Name(), err)
+	}
+	logger.Info("Notification sink enabled: %s", sink.Name())
+	return append(routes, notifier.Route{Sink: sink, Filter: f})
+}
+
+// telegramSettings converts the telegram section of the config to sink settings
+func telegramSettings(cfg config.TelegramSink) notifier.TelegramSettings {
+	return notifier.TelegramSettings{
+		BaseURL:     cfg.BaseURL,
+		Token:       cfg.BotToken,
+		ChatIDs:     cfg.ChatIDs,
+		Proxy:       cfg.Proxy,
+		ParseMode:   cfg.ParseMode,
+		Template:    cfg.Template,
+		Templates:   cfg.Templates,
+		MinInterval: time.Duration(cfg.MinIntervalSeconds) * time.Second,
+	}
+}
+
+// webhookSettings converts a webhook of the config to sink settings
+func webhookSettings(cfg config.WebhookSink) notifier.WebhookSettings {
+	return notifier.WebhookSettings{
+		Name:     cfg.Name,
+		URL:      cfg.URL,
+		Method:   cfg.Method,
+		Format:   cfg.Format,
+		Template: cfg.Template,
+		Form:     cfg.Form,
+		Headers:  cfg.Headers,
+		Secret:   cfg.Secret,
+		Retries:  *cfg.Retries,
+		Backoff:  time.Duration(cfg.RetryBackoffSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"claudecompanion/internal/state"
+	"claudecompanion/internal/statusline"
+)
+
+// followRetryDelay is the delay before reconnecting to a stopped instance in follow mode
+const followRetryDelay = 5 * time.Second
+
+// cmdStatusline prints the remaining quota for status bars (waybar, i3blocks, polybar, tmux)
+// The app not running is a normal state for a bar: the offline status is printed with exit code 0
+func cmdStatusline(args []string) int {
+	var opts cliOptions
+	fs := flag.NewFlagSet("statusline", flag.ContinueOnError)
+	fs.StringVar(&opts.format, "format", statusline.FormatPlain, "Формат: "+strings.Join(statusline.Formats, ", "))
+	fs.IntVar(&opts.port, "port", 0, "Порт локального API (по умолчанию server_port из config.yaml)")
+	follow := fs.Bool("follow", false, "Не завершаться: печатать строку при каждом изменении")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if !statusline.IsFormat(opts.format) || fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Формат должен быть одним из: %s\n", strings.Join(statusline.Formats, ", "))
+		return exitUsage
+	}
+
+	c, err := opts.connect()
+	if err != nil {
+		return opts.fail(err)
+	}
+	colors := &opts.cfg.IconColors
+
+	if !*follow {
+		status := statusline.Offline(colors)
+		if snap, err := c.Usage(); err == nil {
+			status = statusline.New(snap, colors)
+		}
+		fmt.Print(statusline.Render(opts.format, status))
+		return exitOK
+	}
+
+	// Bars read stdout line by line, so identical updates are skipped
+	var last string
+	emit := func(status statusline.Status) {
+		line := statusline.RenderStream(opts.format, status)
+		if line != last {
+			fmt.Print(line)
+			last = line
+		}
+	}
+	for {
+		c.Follow(func(snap *state.Snapshot) {
+			emit(statusline.New(snap, colors))
+		})
+		emit(statusline.Offline(colors))
+		time.Sleep(followRetryDelay)
+	}
+}
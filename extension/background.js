@@ -1,6 +1,7 @@
 // Default settings
 const DEFAULT_PORT = 8383;
 let currentPort = DEFAULT_PORT;
+let pairingToken = '';
 
 // Storage for captured anthropic-client-sha from real browser requests
 let capturedClientSha = null;
@@ -20,10 +21,13 @@ browser.webRequest.onBeforeSendHeaders.addListener(
 );
 
 // Load settings on startup
-browser.storage.local.get(['port']).then((result) => {
+browser.storage.local.get(['port', 'pairingToken']).then((result) => {
   if (result.port) {
     currentPort = result.port;
   }
+  if (result.pairingToken) {
+    pairingToken = result.pairingToken;
+  }
   console.log(`ClaudeCompanion Extension loaded. Port: ${currentPort}`);
 });
 
@@ -33,6 +37,10 @@ browser.storage.onChanged.addListener((changes, area) => {
     currentPort = changes.port.newValue || DEFAULT_PORT;
     console.log(`Port updated to: ${currentPort}`);
   }
+  if (area === 'local' && changes.pairingToken) {
+    pairingToken = changes.pairingToken.newValue || '';
+    console.log('Pairing token updated');
+  }
 });
 
 // Function to get Organization UUID and build usage URL
@@ -171,6 +179,7 @@ async function sendContext() {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-Pairing-Token': pairingToken,
       },
       body: JSON.stringify(payload)
     });
@@ -179,6 +188,17 @@ async function sendContext() {
       const data = await response.json();
       console.log('[ClaudeCompanion] ✅ Context sent successfully:', data);
       return true;
+    } else if (response.status === 401) {
+      console.error('[ClaudeCompanion] ❌ Pairing token missing or invalid');
+      if (browser.notifications) {
+        browser.notifications.create('claudecompanion-pairing', {
+          type: 'basic',
+          iconUrl: 'icon48.png',
+          title: 'ClaudeCompanion: нужен код сопряжения',
+          message: 'Скопируйте код из меню значка в трее и вставьте его в настройках расширения.'
+        });
+      }
+      return false;
     } else {
       console.error('[ClaudeCompanion] ❌ Failed to send context:', response.status, response.statusText);
       return false;
@@ -237,7 +257,7 @@ browser.tabs.onActivated.addListener(async (activeInfo) => {
 browser.runtime.onMessage.addListener((message, sender, sendResponse) => {
   if (message.action === 'testConnection') {
     // Test connection to desktop app
-    fetch(`http://127.0.0.1:${currentPort}/health`)
+    fetch(`http://127.0.0.1:${currentPort}/health`, { headers: { 'X-Pairing-Token': pairingToken } })
       .then(response => response.json())
       .then(data => {
         sendResponse({ success: true, data: data });
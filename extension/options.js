@@ -1,9 +1,12 @@
 // Load saved settings
 function loadSettings() {
-  browser.storage.local.get(['port']).then((result) => {
+  browser.storage.local.get(['port', 'pairingToken']).then((result) => {
     if (result.port) {
       document.getElementById('port').value = result.port;
     }
+    if (result.pairingToken) {
+      document.getElementById('pairingToken').value = result.pairingToken;
+    }
   });
 }
 
@@ -12,13 +15,14 @@ function saveSettings(e) {
   e.preventDefault();
 
   const port = parseInt(document.getElementById('port').value);
+  const pairingToken = document.getElementById('pairingToken').value.trim().toUpperCase();
 
   if (port < 1024 || port > 65535) {
     showStatus('Порт должен быть в диапазоне 1024-65535', 'error');
     return;
   }
 
-  browser.storage.local.set({ port: port }).then(() => {
+  browser.storage.local.set({ port: port, pairingToken: pairingToken }).then(() => {
     showStatus('Настройки сохранены успешно!', 'success');
   }).catch((error) => {
     showStatus('Ошибка сохранения: ' + error.message, 'error');
@@ -28,13 +32,18 @@ function saveSettings(e) {
 // Test connection to desktop app
 function testConnection() {
   const port = parseInt(document.getElementById('port').value);
+  const pairingToken = document.getElementById('pairingToken').value.trim();
   const endpoint = `http://127.0.0.1:${port}/health`;
 
   showStatus('Проверка соединения...', 'info');
 
-  fetch(endpoint)
+  fetch(endpoint, { headers: { 'X-Pairing-Token': pairingToken } })
     .then(response => response.json())
     .then(data => {
+      if (data.paired === false) {
+        showStatus('✗ Соединение есть, но код сопряжения неверный. Скопируйте его из меню значка в трее.', 'error');
+        return;
+      }
       showStatus(`✓ Соединение установлено! Версия: ${data.version || 'unknown'}`, 'success');
     })
     .catch(error => {
@@ -1,12 +1,12 @@
 package api
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
-	"os/exec"
-	"runtime"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -19,260 +19,193 @@ func truncateCookie(cookie string) string {
 	return cookie[:60] + "..." + cookie[len(cookie)-40:]
 }
 
-// truncateArgs returns a truncated version of curl arguments for logging
-// Replaces cookie values with [TRUNCATED] to avoid exposing sensitive data
-func truncateArgs(args []string) []string {
-	truncated := make([]string, len(args))
-	for i, arg := range args {
-		// Truncate cookie header values
-		if i > 0 && args[i-1] == "-H" && len(arg) > 20 && (len(arg) > 100 || (i > 1 && len(args[i-1]) > 0)) {
-			// Check if this looks like a cookie header
-			if len(arg) > 8 && arg[:8] == "Cookie: " {
-				truncated[i] = "Cookie: [TRUNCATED]"
-				continue
-			}
-		}
-		// Keep other arguments as-is
-		if len(arg) > 200 {
-			truncated[i] = arg[:100] + "...[TRUNCATED]..." + arg[len(arg)-50:]
-		} else {
-			truncated[i] = arg
-		}
-	}
-	return truncated
-}
-
-// UsageResponse represents the API response
-type UsageResponse struct {
-	FiveHour struct {
-		Utilization float64    `json:"utilization"`
-		ResetsAt    *time.Time `json:"resets_at"`
-	} `json:"five_hour"`
-	SevenDay struct {
-		Utilization float64    `json:"utilization"`
-		ResetsAt    *time.Time `json:"resets_at"`
-	} `json:"seven_day"`
-}
+// RequestHook is called after every request with the transport name, the request kind
+// ("usage" or "greeting") and how long the transport took, e.g. to record metrics
+type RequestHook func(transport, kind string, duration time.Duration)
 
 // Client handles API requests
 type Client struct {
+	mu             sync.RWMutex
 	cookies        string
 	targetURL      string
 	organizationID string
 	headers        map[string]string // Includes User-Agent
-	proxy          string
-	curlPath       string
-	fullLogging    bool // Enable full logging of cookies and curl commands
+	settings       Settings
+	transport      Transport
+	requestHook    RequestHook
 }
 
 // NewClient creates a new API client
-func NewClient(proxy, curlPath string, fullLogging bool) *Client {
-	return &Client{
-		proxy:       proxy,
-		curlPath:    curlPath,
-		fullLogging: fullLogging,
-	}
+func NewClient(settings Settings) *Client {
+	c := &Client{}
+	c.applySettings(settings)
+	return c
 }
 
 // SetContext updates cookies, target URL, organization ID and headers (includes User-Agent)
 func (c *Client) SetContext(cookies, targetURL, organizationID string, headers map[string]string) {
+	c.mu.Lock()
 	c.cookies = cookies
 	c.targetURL = targetURL
 	c.organizationID = organizationID
 	c.headers = headers
+	fullLogging := c.settings.FullLogging
+	c.mu.Unlock()
+
 	log.Printf("Context updated: URL=%s, OrgID=%s, Cookies length=%d, Headers count=%d",
 		targetURL, organizationID, len(cookies), len(headers))
 
 	// Log cookie preview (full or truncated based on settings)
-	if c.fullLogging {
+	if fullLogging {
 		log.Printf("  Cookie (full): %s", cookies)
 	} else {
 		log.Printf("  Cookie preview: %s", truncateCookie(cookies))
 	}
 }
 
-// UpdateSettings updates proxy, curl path and full logging flag without clearing context (cookies, headers, etc.)
-func (c *Client) UpdateSettings(proxy, curlPath string, fullLogging bool) {
-	c.proxy = proxy
-	c.curlPath = curlPath
-	c.fullLogging = fullLogging
-	log.Printf("Settings updated: Proxy=%s, CurlPath=%s, FullLogging=%v (context preserved)", proxy, curlPath, fullLogging)
+// UpdateSettings updates transport, proxy, curl path, timeout and full logging flag
+// without clearing context (cookies, headers, etc.)
+func (c *Client) UpdateSettings(settings Settings) {
+	c.applySettings(settings)
+	log.Printf("Settings updated: Transport=%s, Proxy=%s, CurlPath=%s, Timeout=%s, FullLogging=%v (context preserved)",
+		c.TransportName(), settings.Proxy, settings.CurlPath, settings.Timeout, settings.FullLogging)
 }
 
-// HasContext returns true if cookies are set
-func (c *Client) HasContext() bool {
-	return c.cookies != "" && c.targetURL != ""
-}
-
-// GetUsage fetches the current usage from the API using curl
-func (c *Client) GetUsage() (*UsageResponse, error) {
-	if !c.HasContext() {
-		return nil, fmt.Errorf("no context set (cookies not received from extension)")
+// applySettings stores settings and recreates the transport
+// Falls back to curl if the configured transport can't be created
+func (c *Client) applySettings(settings Settings) {
+	transport, err := NewTransport(settings)
+	if err != nil {
+		log.Printf("Failed to create %q transport: %v, falling back to curl", settings.Transport, err)
+		transport = newCurlTransport(settings)
 	}
 
-	// Use curl directly (HTTP client removed - curl works better with proxy)
-	return c.fetchWithCurl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = settings
+	c.transport = transport
 }
 
-// fetchWithCurl performs the request using system curl
-func (c *Client) fetchWithCurl() (*UsageResponse, error) {
-	curlPath := c.getCurlPath()
-
-	args := []string{
-		"-X", "GET",
-		c.targetURL,
-		"-H", fmt.Sprintf("Cookie: %s", c.cookies), // All cookies from browser
-	}
+// SetRequestHook sets the function called after every request
+func (c *Client) SetRequestHook(hook RequestHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requestHook = hook
+}
 
-	// Add all browser headers to emulate real browser request
-	for key, value := range c.headers {
-		// Skip Accept-Encoding because curl doesn't handle gzip automatically
-		if key == "Accept-Encoding" {
-			continue
-		}
-		// Add all headers including User-Agent from browser
-		args = append(args, "-H", fmt.Sprintf("%s: %s", key, value))
+// observe reports a finished request to the hook
+func (c *Client) observe(transport, kind string, duration time.Duration) {
+	c.mu.RLock()
+	hook := c.requestHook
+	c.mu.RUnlock()
+	if hook != nil {
+		hook(transport, kind, duration)
 	}
+}
 
-	// Add proxy if configured
-	if c.proxy != "" {
-		args = append([]string{"-x", c.proxy}, args...)
-	}
+// TransportName returns the name of the active transport
+func (c *Client) TransportName() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.transport.Name()
+}
 
-	// Log curl command
-	log.Printf("========================================")
-	log.Printf("CURL Request:")
-	log.Printf("  Command: %s", curlPath)
-	log.Printf("  URL: %s", c.targetURL)
-	log.Printf("  Proxy: %s", c.proxy)
-
-	// Log cookies (full or truncated based on settings)
-	if c.fullLogging {
-		log.Printf("  Cookie (full): %s", c.cookies)
-		log.Printf("  Full command: %s %v", curlPath, args)
-	} else {
-		log.Printf("  Cookie preview: %s", truncateCookie(c.cookies))
-		log.Printf("  Command preview: %s %v", curlPath, truncateArgs(args))
-	}
-	log.Printf("========================================")
+// HasContext returns true if cookies are set
+func (c *Client) HasContext() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cookies != "" && c.targetURL != ""
+}
 
-	cmd := exec.Command(curlPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// OrganizationID returns the organization ID received from the extension
+func (c *Client) OrganizationID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.organizationID
+}
 
-	// Hide console window on Windows
-	hideWindow(cmd)
+// newRequest builds a request carrying the browser context
+func (c *Client) newRequest(method, url string, body []byte) (*Request, Transport) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Request{
+		Method:  method,
+		URL:     url,
+		Cookies: c.cookies,
+		Headers: c.headers,
+		Body:    body,
+	}, c.transport
+}
 
-	if err := cmd.Run(); err != nil {
-		log.Printf("CURL execution failed: %v", err)
-		log.Printf("CURL stderr: %s", stderr.String())
-		log.Printf("CURL stdout: %s", stdout.String())
-		log.Printf("========================================")
-		return nil, fmt.Errorf("curl execution failed: %w, stderr: %s", err, stderr.String())
+// GetUsage fetches the current usage from the API using the configured transport
+// Failures are returned as *FetchError
+func (c *Client) GetUsage() (*UsageResponse, error) {
+	if !c.HasContext() {
+		return nil, fmt.Errorf("no context set (cookies not received from extension)")
 	}
 
-	// With -v flag, headers go to stderr, body goes to stdout
-	log.Printf("CURL Response Headers (stderr):")
-	log.Printf("%s", stderr.String())
-	log.Printf("CURL Response Body (stdout):")
-	log.Printf("%s", stdout.String())
-	log.Printf("========================================")
+	c.mu.RLock()
+	targetURL := c.targetURL
+	c.mu.RUnlock()
 
-	// Parse JSON from stdout (body only)
-	var usage UsageResponse
-	if err := json.Unmarshal(stdout.Bytes(), &usage); err != nil {
-		log.Printf("Failed to parse CURL JSON: %v", err)
-		log.Printf("JSON body: %s", stdout.String())
-		return nil, fmt.Errorf("failed to parse curl output: %w, output: %s", err, stdout.String())
+	req, transport := c.newRequest(http.MethodGet, targetURL, nil)
+	start := time.Now()
+	resp, err := transport.Do(req)
+	c.observe(transport.Name(), "usage", time.Since(start))
+	if err != nil {
+		return nil, classifyTransportError(err)
 	}
 
-	log.Printf("CURL Success! Parsed usage data")
-	log.Printf("========================================")
-
-	return &usage, nil
-}
-
-// getCurlPath returns the platform-specific curl path
-func (c *Client) getCurlPath() string {
-	// Use custom curl path if configured
-	if c.curlPath != "" {
-		return c.curlPath
+	if fe := classifyResponse(resp); fe != nil {
+		log.Printf("Usage request failed: %v", fe)
+		return nil, fe
 	}
 
-	// Otherwise use platform defaults
-	switch runtime.GOOS {
-	case "windows":
-		return "curl.exe"
-	case "darwin":
-		// macOS: use Homebrew curl to avoid Cloudflare issues
-		return "/opt/homebrew/opt/curl/bin/curl"
-	default:
-		// Linux and others: use system curl
-		return "curl"
+	// Parse JSON body
+	var usage UsageResponse
+	if err := json.Unmarshal(resp.Body, &usage); err != nil {
+		log.Printf("Failed to parse usage JSON: %v", err)
+		log.Printf("JSON body: %s", resp.Body)
+		return nil, &FetchError{
+			Kind:       ErrorSchema,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("failed to parse %s output: %w, output: %s", transport.Name(), err, truncateBody(resp.Body)),
+		}
 	}
-}
 
-// GetInvertedValue returns the inverted utilization value (100 - utilization)
-func (ur *UsageResponse) GetInvertedValue() int {
-	utilization := ur.FiveHour.Utilization
-	remaining := 100 - utilization
-	if remaining < 0 {
-		return 0
-	}
-	if remaining > 100 {
-		return 100
+	if len(usage.Windows) == 0 {
+		log.Printf("Usage JSON contains no limit windows: %s", resp.Body)
+		return nil, &FetchError{
+			Kind:       ErrorSchema,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("no usage windows in response: %s", truncateBody(resp.Body)),
+		}
 	}
-	return int(remaining)
-}
 
-// GetResetTime returns the time when the quota resets
-func (ur *UsageResponse) GetResetTime() *time.Time {
-	return ur.FiveHour.ResetsAt
-}
+	log.Printf("Success! Parsed usage data (transport: %s)", transport.Name())
 
-// GetSevenDayValue returns the inverted 7-day utilization value
-func (ur *UsageResponse) GetSevenDayInvertedValue() int {
-	utilization := ur.SevenDay.Utilization
-	remaining := 100 - utilization
-	if remaining < 0 {
-		return 0
-	}
-	if remaining > 100 {
-		return 100
-	}
-	return int(remaining)
+	return &usage, nil
 }
 
-// FormatTooltip creates a formatted tooltip string
-func (ur *UsageResponse) FormatTooltip() string {
-	fiveHourUtilization := ur.FiveHour.Utilization
-	sevenDayUtilization := ur.SevenDay.Utilization
-
-	var fiveHourResetTime string
-	var sevenDayResetTime string
+// baseURL returns scheme and host of the target URL (https://claude.ai by default)
+func (c *Client) baseURL() string {
+	c.mu.RLock()
+	targetURL := c.targetURL
+	c.mu.RUnlock()
 
-	if ur.FiveHour.ResetsAt != nil {
-		fiveHourResetTime = ur.FiveHour.ResetsAt.Local().Format("15:04")
-	} else {
-		fiveHourResetTime = "—"
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "https://claude.ai"
 	}
+	return u.Scheme + "://" + u.Host
+}
 
-	if ur.SevenDay.ResetsAt != nil {
-		sevenDayResetTime = ur.SevenDay.ResetsAt.Local().Format("02.01 15:04")
-	} else {
-		sevenDayResetTime = "—"
+// truncateBody returns a short preview of response body for errors
+func truncateBody(body []byte) string {
+	if len(body) <= 300 {
+		return string(body)
 	}
-
-	// Add current timestamp for last update
-	currentTime := time.Now().Format("15:04 02.01")
-
-	// Use \r\n for Windows multiline tooltips
-	// Compact format to fit Windows tooltip limit (~63 chars per line)
-	return fmt.Sprintf("5ч: %.0f%% (%s) | 7д: %.0f%% (%s)\r\nОбновлено: %s",
-		fiveHourUtilization, fiveHourResetTime,
-		sevenDayUtilization, sevenDayResetTime,
-		currentTime)
+	return string(body[:300]) + "...[TRUNCATED]"
 }
 
 // SendGreeting sends a greeting message to specified chat
@@ -281,7 +214,11 @@ func (c *Client) SendGreeting(chatID, text string) error {
 		return fmt.Errorf("no context set (cookies not received from extension)")
 	}
 
-	if c.organizationID == "" {
+	c.mu.RLock()
+	organizationID := c.organizationID
+	c.mu.RUnlock()
+
+	if organizationID == "" {
 		return fmt.Errorf("organization ID not set")
 	}
 
@@ -289,11 +226,9 @@ func (c *Client) SendGreeting(chatID, text string) error {
 		return fmt.Errorf("chat ID not specified")
 	}
 
-	curlPath := c.getCurlPath()
-
 	// Build URL
-	url := fmt.Sprintf("https://claude.ai/api/organizations/%s/chat_conversations/%s/completion",
-		c.organizationID, chatID)
+	url := fmt.Sprintf("%s/api/organizations/%s/chat_conversations/%s/completion",
+		c.baseURL(), organizationID, chatID)
 
 	// Build JSON body
 	payload := map[string]string{"prompt": text}
@@ -302,30 +237,6 @@ func (c *Client) SendGreeting(chatID, text string) error {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	args := []string{
-		"-X", "POST",
-		url,
-		"-H", "Content-Type: application/json",
-		"-H", fmt.Sprintf("Cookie: %s", c.cookies), // All cookies from browser
-		"-d", string(payloadBytes),
-	}
-
-	// Add all browser headers to emulate real browser request
-	for key, value := range c.headers {
-		// Skip Content-Type as it's already added above
-		// Skip Accept-Encoding because curl doesn't handle gzip automatically
-		if key == "Content-Type" || key == "Accept-Encoding" {
-			continue
-		}
-		// Add all headers including User-Agent from browser
-		args = append(args, "-H", fmt.Sprintf("%s: %s", key, value))
-	}
-
-	// Add proxy if configured
-	if c.proxy != "" {
-		args = append([]string{"-x", c.proxy}, args...)
-	}
-
 	// Log greeting request
 	log.Printf("========================================")
 	log.Printf("GREETING Request:")
@@ -334,24 +245,24 @@ func (c *Client) SendGreeting(chatID, text string) error {
 	log.Printf("  Chat ID: %s", chatID)
 	log.Printf("========================================")
 
-	cmd := exec.Command(curlPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Hide console window on Windows
-	hideWindow(cmd)
+	req, transport := c.newRequest(http.MethodPost, url, payloadBytes)
+	start := time.Now()
+	resp, err := transport.Do(req)
+	c.observe(transport.Name(), "greeting", time.Since(start))
+	if err != nil {
+		log.Printf("GREETING: %s request failed: %v", transport.Name(), err)
+		log.Printf("========================================")
+		return fmt.Errorf("greeting request failed: %w", err)
+	}
 
-	if err := cmd.Run(); err != nil {
-		log.Printf("GREETING: CURL execution failed: %v", err)
-		log.Printf("GREETING: CURL stderr: %s", stderr.String())
-		log.Printf("GREETING: CURL stdout: %s", stdout.String())
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("GREETING: Unexpected HTTP status %d", resp.StatusCode)
 		log.Printf("========================================")
-		return fmt.Errorf("greeting request failed: %w, stderr: %s", err, stderr.String())
+		return fmt.Errorf("greeting request failed: HTTP status %d, body: %s", resp.StatusCode, truncateBody(resp.Body))
 	}
 
 	log.Printf("GREETING: Success!")
-	log.Printf("GREETING: Response: %s", stdout.String())
+	log.Printf("GREETING: Response: %s", resp.Body)
 	log.Printf("========================================")
 
 	return nil
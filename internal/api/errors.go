@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies usage fetch failures
+type ErrorKind int
+
+const (
+	ErrorUnknown        ErrorKind = iota
+	ErrorSessionExpired           // 401/403: cookies are no longer accepted
+	ErrorCloudflare               // Cloudflare challenge page instead of JSON
+	ErrorRateLimited              // 429 Too Many Requests
+	ErrorServer                   // 5xx from Claude.ai
+	ErrorNetwork                  // DNS, connect, TLS, proxy or timeout failure
+	ErrorSchema                   // Response isn't the expected usage JSON
+)
+
+// String returns a stable machine-readable kind name
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorSessionExpired:
+		return "session_expired"
+	case ErrorCloudflare:
+		return "cloudflare"
+	case ErrorRateLimited:
+		return "rate_limited"
+	case ErrorServer:
+		return "server_error"
+	case ErrorNetwork:
+		return "network"
+	case ErrorSchema:
+		return "schema"
+	default:
+		return "unknown"
+	}
+}
+
+// NeedsUserAction returns true for errors that won't go away without the user (re-login, captcha)
+func (k ErrorKind) NeedsUserAction() bool {
+	return k == ErrorSessionExpired || k == ErrorCloudflare
+}
+
+// FetchError is a classified usage fetch failure
+type FetchError struct {
+	Kind       ErrorKind
+	StatusCode int           // HTTP status, 0 if no response was received
+	RetryAfter time.Duration // Parsed Retry-After header (rate limited only)
+	Err        error
+}
+
+// Error implements the error interface
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s (HTTP %d): %v", e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Title returns a notification title describing the failure
+func (e *FetchError) Title() string {
+	switch e.Kind {
+	case ErrorSessionExpired:
+		return "Сессия устарела"
+	case ErrorCloudflare:
+		return "Проверка Cloudflare"
+	case ErrorRateLimited:
+		return "Слишком много запросов"
+	case ErrorServer:
+		return "Сбой на стороне Claude.ai"
+	case ErrorNetwork:
+		return "Нет соединения"
+	case ErrorSchema:
+		return "Неожиданный ответ API"
+	default:
+		return "Ошибка запроса к API"
+	}
+}
+
+// Description returns a short description of what went wrong (fits a tooltip line)
+func (e *FetchError) Description() string {
+	switch e.Kind {
+	case ErrorSessionExpired:
+		return fmt.Sprintf("Сайт отклонил авторизацию (HTTP %d)", e.StatusCode)
+	case ErrorCloudflare:
+		return "Cloudflare требует проверку браузера"
+	case ErrorRateLimited:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("Лимит запросов (HTTP 429), повтор через %s", FormatDuration(e.RetryAfter))
+		}
+		return "Лимит запросов (HTTP 429)"
+	case ErrorServer:
+		return fmt.Sprintf("Ошибка сервера (HTTP %d)", e.StatusCode)
+	case ErrorNetwork:
+		return "Не удалось подключиться к claude.ai"
+	case ErrorSchema:
+		return "Формат ответа не распознан"
+	default:
+		return "Ошибка подключения к API"
+	}
+}
+
+// Hint suggests how to fix the failure
+func (e *FetchError) Hint() string {
+	switch e.Kind {
+	case ErrorSessionExpired:
+		return "Зайдите на claude.ai, чтобы обновить авторизацию 🔐"
+	case ErrorCloudflare:
+		return "Откройте claude.ai в браузере и пройдите проверку"
+	case ErrorRateLimited:
+		return "Подождите, опрос продолжится сам"
+	case ErrorServer:
+		return "Обычно это временно, подождите"
+	case ErrorNetwork:
+		return "Проверьте интернет и настройки прокси"
+	case ErrorSchema:
+		return "Возможно, API изменился — обновите приложение"
+	default:
+		return "Подробности в логе"
+	}
+}
+
+// ClassifyError converts any error into a FetchError
+// Errors that are already classified are returned as-is
+func ClassifyError(err error) *FetchError {
+	if err == nil {
+		return nil
+	}
+	var fe *FetchError
+	if errors.As(err, &fe) {
+		return fe
+	}
+	return &FetchError{Kind: ErrorUnknown, Err: err}
+}
+
+// classifyTransportError classifies an error returned by Transport.Do (no HTTP response)
+func classifyTransportError(err error) *FetchError {
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		// curl binary is missing or not executable - configuration problem, not network
+		return &FetchError{Kind: ErrorUnknown, Err: err}
+	}
+
+	var exitErr *exec.ExitError
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &exitErr) || errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return &FetchError{Kind: ErrorNetwork, Err: err}
+	}
+
+	return &FetchError{Kind: ErrorUnknown, Err: err}
+}
+
+// classifyResponse returns a FetchError for responses that don't carry usage JSON, nil otherwise
+func classifyResponse(resp *Response) *FetchError {
+	code := resp.StatusCode
+
+	if isCloudflareChallenge(resp) {
+		return &FetchError{Kind: ErrorCloudflare, StatusCode: code, Err: fmt.Errorf("challenge page: %s", truncateBody(resp.Body))}
+	}
+
+	// Curl may not report a status (unparsed headers), let JSON parsing decide
+	if code == 0 || (code >= 200 && code < 300) {
+		return nil
+	}
+
+	err := fmt.Errorf("unexpected HTTP status %d, body: %s", code, truncateBody(resp.Body))
+
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return &FetchError{Kind: ErrorSessionExpired, StatusCode: code, Err: err}
+	case code == http.StatusTooManyRequests:
+		return &FetchError{
+			Kind:       ErrorRateLimited,
+			StatusCode: code,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Err:        err,
+		}
+	case code == http.StatusProxyAuthRequired:
+		return &FetchError{Kind: ErrorNetwork, StatusCode: code, Err: err}
+	case code >= 500:
+		return &FetchError{Kind: ErrorServer, StatusCode: code, Err: err}
+	default:
+		return &FetchError{Kind: ErrorUnknown, StatusCode: code, Err: err}
+	}
+}
+
+// isCloudflareChallenge detects Cloudflare "Just a moment..." interstitial pages
+func isCloudflareChallenge(resp *Response) bool {
+	if resp.Header != nil && strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge") {
+		return true
+	}
+
+	body := bytes.ToLower(resp.Body)
+	if !bytes.Contains(body, []byte("<html")) && !bytes.Contains(body, []byte("<!doctype html")) {
+		return false
+	}
+	for _, marker := range []string{"just a moment", "challenge-platform", "cf_chl_", "cf-challenge", "attention required! | cloudflare"} {
+		if bytes.Contains(body, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses Retry-After in delay-seconds or HTTP-date form
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// FormatDuration formats a duration compactly in Russian: "45с", "4м", "1ч20м"
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		seconds := int(d.Round(time.Second).Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		return fmt.Sprintf("%dс", seconds)
+	}
+	d = d.Round(time.Minute)
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dм", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dч", hours)
+	default:
+		return fmt.Sprintf("%dч%dм", hours, minutes)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		wantKind   ErrorKind
+		wantNil    bool
+		retryAfter time.Duration
+	}{
+		{name: "ok", status: 200, body: `{"five_hour":null}`, wantNil: true},
+		{name: "no status from curl", status: 0, body: `{}`, wantNil: true},
+		{name: "unauthorized", status: 401, body: `{"error":"x"}`, wantKind: ErrorSessionExpired},
+		{name: "forbidden", status: 403, body: `{"error":"x"}`, wantKind: ErrorSessionExpired},
+		{name: "rate limited", status: 429, header: http.Header{"Retry-After": {"30"}}, wantKind: ErrorRateLimited, retryAfter: 30 * time.Second},
+		{name: "rate limited without retry-after", status: 429, wantKind: ErrorRateLimited},
+		{name: "proxy auth", status: 407, wantKind: ErrorNetwork},
+		{name: "server error", status: 502, body: "bad gateway", wantKind: ErrorServer},
+		{name: "other client error", status: 404, wantKind: ErrorUnknown},
+		{name: "cloudflare header", status: 403, header: http.Header{"Cf-Mitigated": {"challenge"}}, wantKind: ErrorCloudflare},
+		{name: "cloudflare page", status: 503, body: "<!DOCTYPE html><title>Just a moment...</title>", wantKind: ErrorCloudflare},
+		{name: "cloudflare page with 200", status: 200, body: "<html><script src=/cdn-cgi/challenge-platform/x></script></html>", wantKind: ErrorCloudflare},
+		{name: "html without challenge", status: 500, body: "<html>Internal error</html>", wantKind: ErrorServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			got := classifyResponse(&Response{StatusCode: tt.status, Header: header, Body: []byte(tt.body)})
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("classifyResponse() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("classifyResponse() = nil, want %s", tt.wantKind)
+			}
+			if got.Kind != tt.wantKind {
+				t.Errorf("Kind = %s, want %s", got.Kind, tt.wantKind)
+			}
+			if got.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", got.StatusCode, tt.status)
+			}
+			if got.RetryAfter != tt.retryAfter {
+				t.Errorf("RetryAfter = %s, want %s", got.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{"Fri, 02 Jan 2026 10:01:30 GMT", 90 * time.Second},
+		{"Fri, 02 Jan 2026 09:59:00 GMT", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
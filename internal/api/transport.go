@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Transport names accepted in config (transport: curl | http)
+const (
+	TransportCurl = "curl"
+	TransportHTTP = "http"
+)
+
+// Request describes a single request performed by a Transport
+type Request struct {
+	Method  string
+	URL     string
+	Cookies string            // All cookies from browser
+	Headers map[string]string // Browser headers (includes User-Agent)
+	Body    []byte            // Optional JSON body
+}
+
+// Response is the raw result of a Transport request
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Transport performs HTTP requests on behalf of Client
+type Transport interface {
+	// Name returns the transport name used in logs and config
+	Name() string
+	// Do performs the request and returns the raw response
+	Do(req *Request) (*Response, error)
+}
+
+// Settings holds the client settings that come from config
+type Settings struct {
+	Transport   string        // "curl" (default) or "http"
+	Proxy       string        // Proxy URL, empty for none
+	CurlPath    string        // Custom path to curl binary (curl transport only)
+	Timeout     time.Duration // Per-request timeout, 0 = no timeout
+	FullLogging bool          // Enable full logging of cookies and commands
+}
+
+// NewTransport creates the transport selected in settings
+func NewTransport(settings Settings) (Transport, error) {
+	switch settings.Transport {
+	case "", TransportCurl:
+		return newCurlTransport(settings), nil
+	case TransportHTTP:
+		return newHTTPTransport(settings)
+	default:
+		return nil, fmt.Errorf("unknown transport %q (expected %q or %q)", settings.Transport, TransportCurl, TransportHTTP)
+	}
+}
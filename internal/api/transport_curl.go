@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/textproto"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// curlTransport performs requests by running the system curl binary
+type curlTransport struct {
+	settings Settings
+}
+
+// newCurlTransport creates a curl-based transport
+func newCurlTransport(settings Settings) *curlTransport {
+	return &curlTransport{settings: settings}
+}
+
+// Name returns the transport name
+func (t *curlTransport) Name() string {
+	return TransportCurl
+}
+
+// args builds the curl command line
+// Cookies, headers, body and proxy go through stdin (--config -), so they don't show up in ps
+func (t *curlTransport) args(req *Request) []string {
+	args := []string{
+		"--config", "-",
+		"-X", req.Method,
+		req.URL,
+		"-D", "-", // Dump response headers to stdout before the body
+	}
+
+	// Limit total request time; curl accepts fractional seconds and treats 0 as no limit
+	if t.settings.Timeout > 0 {
+		args = append(args, "--max-time", strconv.FormatFloat(t.settings.Timeout.Seconds(), 'f', -1, 64))
+	}
+	return args
+}
+
+// Do performs the request using system curl
+func (t *curlTransport) Do(req *Request) (*Response, error) {
+	curlPath := t.getCurlPath()
+	args := t.args(req)
+	config := curlConfig(req, t.settings.Proxy)
+
+	// Log curl command
+	log.Printf("========================================")
+	log.Printf("CURL Request:")
+	log.Printf("  Command: %s %v", curlPath, args)
+	log.Printf("  URL: %s", req.URL)
+	log.Printf("  Proxy: %s", t.settings.Proxy)
+
+	// Log cookies (full or truncated based on settings)
+	if t.settings.FullLogging {
+		log.Printf("  Cookie (full): %s", req.Cookies)
+		log.Printf("  Config (full):\n%s", config)
+	} else {
+		log.Printf("  Cookie preview: %s", truncateCookie(req.Cookies))
+	}
+	log.Printf("========================================")
+
+	cmd := exec.Command(curlPath, args...)
+	cmd.Stdin = bytes.NewReader(config)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Hide console window on Windows
+	hideWindow(cmd)
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("CURL execution failed: %v", err)
+		log.Printf("CURL stderr: %s", stderr.String())
+		log.Printf("CURL stdout: %s", stdout.String())
+		log.Printf("========================================")
+		return nil, fmt.Errorf("curl execution failed: %w, stderr: %s", err, stderr.String())
+	}
+
+	resp, err := parseCurlOutput(stdout.Bytes())
+	if err != nil {
+		log.Printf("Failed to parse CURL output: %v", err)
+		log.Printf("CURL stdout: %s", stdout.String())
+		log.Printf("========================================")
+		return nil, err
+	}
+
+	log.Printf("CURL Response Status: %d", resp.StatusCode)
+	log.Printf("CURL Response Body:")
+	log.Printf("%s", resp.Body)
+	log.Printf("========================================")
+
+	return resp, nil
+}
+
+// getCurlPath returns the platform-specific curl path
+func (t *curlTransport) getCurlPath() string {
+	// Use custom curl path if configured
+	if t.settings.CurlPath != "" {
+		return t.settings.CurlPath
+	}
+
+	// Otherwise use platform defaults
+	switch runtime.GOOS {
+	case "windows":
+		return "curl.exe"
+	case "darwin":
+		// macOS: use Homebrew curl to avoid Cloudflare issues
+		return "/opt/homebrew/opt/curl/bin/curl"
+	default:
+		// Linux and others: use system curl
+		return "curl"
+	}
+}
+
+// parseCurlOutput splits "-D -" output into status, headers and body
+// Proxy CONNECT and 1xx responses produce several header blocks, the last one wins
+func parseCurlOutput(out []byte) (*Response, error) {
+	resp := &Response{Header: http.Header{}}
+
+	for bytes.HasPrefix(out, []byte("HTTP/")) {
+		end := bytes.Index(out, []byte("\r\n\r\n"))
+		sepLen := 4
+		if end < 0 {
+			end = bytes.Index(out, []byte("\n\n"))
+			sepLen = 2
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("malformed curl output: unterminated header block")
+		}
+
+		reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(out[:end+sepLen])))
+		statusLine, err := reader.ReadLine()
+		if err != nil {
+			return nil, fmt.Errorf("malformed curl output: %w", err)
+		}
+		// Status line: "HTTP/1.1 200 OK" or "HTTP/2 200"
+		fields := strings.Fields(statusLine)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed status line: %q", statusLine)
+		}
+		code, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed status line: %q", statusLine)
+		}
+
+		header, err := reader.ReadMIMEHeader()
+		if err != nil && len(header) == 0 {
+			header = textproto.MIMEHeader{}
+		}
+
+		resp.StatusCode = code
+		resp.Header = http.Header(header)
+		out = out[end+sepLen:]
+	}
+
+	resp.Body = out
+	return resp, nil
+}
+
+// curlConfig builds the curl config read from stdin: cookies, browser headers, body and proxy
+func curlConfig(req *Request, proxy string) []byte {
+	var b bytes.Buffer
+	writeOption := func(name, value string) {
+		fmt.Fprintf(&b, "%s = %s\n", name, curlQuote(value))
+	}
+
+	if proxy != "" {
+		writeOption("proxy", proxy)
+	}
+	writeOption("header", "Cookie: "+req.Cookies) // All cookies from browser
+
+	if len(req.Body) > 0 {
+		writeOption("header", "Content-Type: application/json")
+		writeOption("data-raw", string(req.Body))
+	}
+
+	// Add all browser headers to emulate real browser request
+	for key, value := range req.Headers {
+		// Skip Accept-Encoding because curl doesn't handle gzip automatically
+		if key == "Accept-Encoding" {
+			continue
+		}
+		// Skip Content-Type for requests with body as it's already added above
+		if key == "Content-Type" && len(req.Body) > 0 {
+			continue
+		}
+		// Add all headers including User-Agent from browser
+		writeOption("header", fmt.Sprintf("%s: %s", key, value))
+	}
+
+	return b.Bytes()
+}
+
+// curlQuote quotes a value for a curl config file
+func curlQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\v", `\v`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Browser-like defaults used when the extension didn't provide the header
+const (
+	defaultUserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:143.0) Gecko/20100101 Firefox/143.0"
+	defaultAccept         = "*/*"
+	defaultAcceptLanguage = "en-US,en;q=0.5"
+	acceptEncoding        = "gzip, deflate, br"
+)
+
+// skippedHTTPHeaders are managed by net/http itself and must not be copied from browser
+var skippedHTTPHeaders = map[string]bool{
+	"Host":              true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Accept-Encoding":   true,
+	"Transfer-Encoding": true,
+}
+
+// httpTransport performs requests with the Go net/http client
+type httpTransport struct {
+	client   *http.Client
+	settings Settings
+}
+
+// newHTTPTransport creates a net/http based transport with proxy and timeout
+func newHTTPTransport(settings Settings) (*httpTransport, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Use configured proxy, otherwise fall back to HTTP(S)_PROXY environment (same as curl)
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", settings.Proxy, err)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &httpTransport{
+		client: &http.Client{
+			Transport: base,
+			Timeout:   settings.Timeout,
+		},
+		settings: settings,
+	}, nil
+}
+
+// Name returns the transport name
+func (t *httpTransport) Name() string {
+	return TransportHTTP
+}
+
+// Do performs the request using net/http
+func (t *httpTransport) Do(req *Request) (*Response, error) {
+	var body io.Reader
+	if len(req.Body) > 0 {
+		body = bytes.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	// Add all browser headers to emulate real browser request
+	for key, value := range req.Headers {
+		if skippedHTTPHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		httpReq.Header.Set(key, value)
+	}
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", defaultUserAgent)
+	}
+	if httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", defaultAccept)
+	}
+	if httpReq.Header.Get("Accept-Language") == "" {
+		httpReq.Header.Set("Accept-Language", defaultAcceptLanguage)
+	}
+	if len(req.Body) > 0 {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	// Setting Accept-Encoding explicitly disables transparent gzip in net/http,
+	// so the body is decoded manually below (this also adds brotli support)
+	httpReq.Header.Set("Accept-Encoding", acceptEncoding)
+	httpReq.Header.Set("Cookie", req.Cookies)
+
+	log.Printf("========================================")
+	log.Printf("HTTP Request:")
+	log.Printf("  %s %s", req.Method, req.URL)
+	log.Printf("  Proxy: %s", t.settings.Proxy)
+	if t.settings.FullLogging {
+		log.Printf("  Cookie (full): %s", req.Cookies)
+	} else {
+		log.Printf("  Cookie preview: %s", truncateCookie(req.Cookies))
+	}
+	log.Printf("========================================")
+
+	httpResp, err := t.client.Do(httpReq)
+	if err != nil {
+		log.Printf("HTTP request failed: %v", err)
+		log.Printf("========================================")
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := readDecodedBody(httpResp)
+	if err != nil {
+		log.Printf("Failed to read HTTP response body: %v", err)
+		log.Printf("========================================")
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	log.Printf("HTTP Response Status: %s", httpResp.Status)
+	log.Printf("HTTP Response Body:")
+	log.Printf("%s", respBody)
+	log.Printf("========================================")
+
+	return &Response{
+		StatusCode: httpResp.StatusCode,
+		Header:     httpResp.Header,
+		Body:       respBody,
+	}, nil
+}
+
+// readDecodedBody reads the response body and decodes gzip, deflate or brotli encoding
+func readDecodedBody(resp *http.Response) ([]byte, error) {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	var reader io.Reader
+	switch encoding {
+	case "", "identity":
+		return raw, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		// "deflate" is zlib-wrapped per RFC, but some servers send raw deflate
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			reader = flate.NewReader(bytes.NewReader(raw))
+		} else {
+			defer zr.Close()
+			reader = zr
+		}
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(raw))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestParseCurlOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		out        string
+		wantStatus int
+		wantHeader map[string]string
+		wantBody   string
+		wantErr    bool
+	}{
+		{
+			name:       "http/1.1",
+			out:        "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"a\":1}",
+			wantStatus: 200,
+			wantHeader: map[string]string{"Content-Type": "application/json"},
+			wantBody:   `{"a":1}`,
+		},
+		{
+			name:       "http/2 without reason",
+			out:        "HTTP/2 429\r\nretry-after: 60\r\n\r\nslow down",
+			wantStatus: 429,
+			wantHeader: map[string]string{"Retry-After": "60"},
+			wantBody:   "slow down",
+		},
+		{
+			name:       "proxy connect block",
+			out:        "HTTP/1.1 200 Connection established\r\n\r\nHTTP/2 401\r\ncontent-type: application/json\r\n\r\n{}",
+			wantStatus: 401,
+			wantHeader: map[string]string{"Content-Type": "application/json"},
+			wantBody:   "{}",
+		},
+		{
+			name:       "100 continue",
+			out:        "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 503 Service Unavailable\r\n\r\n",
+			wantStatus: 503,
+		},
+		{
+			name:       "bare newlines",
+			out:        "HTTP/1.1 200 OK\nX-Test: 1\n\nbody",
+			wantStatus: 200,
+			wantHeader: map[string]string{"X-Test": "1"},
+			wantBody:   "body",
+		},
+		{
+			name:     "no headers",
+			out:      `{"a":1}`,
+			wantBody: `{"a":1}`,
+		},
+		{
+			name:    "unterminated headers",
+			out:     "HTTP/1.1 200 OK\r\nContent-Type: text/plain",
+			wantErr: true,
+		},
+		{
+			name:    "malformed status",
+			out:     "HTTP/1.1 abc\r\n\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseCurlOutput([]byte(tt.out))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCurlOutput() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCurlOutput() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			for key, want := range tt.wantHeader {
+				if got := resp.Header.Get(key); got != want {
+					t.Errorf("Header %s = %q, want %q", key, got, want)
+				}
+			}
+			if string(resp.Body) != tt.wantBody {
+				t.Errorf("Body = %q, want %q", resp.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportDecoding(t *testing.T) {
+	const body = `{"five_hour":{"utilization":42}}`
+
+	tests := []struct {
+		encoding string
+		encode   func(w io.Writer) io.WriteCloser
+	}{
+		{"", nil},
+		{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{"br", func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }},
+	}
+
+	for _, tt := range tests {
+		t.Run("encoding "+tt.encoding, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Cookie"); got != "sessionKey=abc" {
+					t.Errorf("Cookie = %q", got)
+				}
+				if got := r.Header.Get("Accept-Encoding"); got != acceptEncoding {
+					t.Errorf("Accept-Encoding = %q", got)
+				}
+				if tt.encode == nil {
+					io.WriteString(w, body)
+					return
+				}
+				w.Header().Set("Content-Encoding", tt.encoding)
+				enc := tt.encode(w)
+				io.WriteString(enc, body)
+				enc.Close()
+			}))
+			defer srv.Close()
+
+			transport, err := newHTTPTransport(Settings{Transport: TransportHTTP})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.Do(&Request{Method: "GET", URL: srv.URL, Cookies: "sessionKey=abc"})
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("StatusCode = %d", resp.StatusCode)
+			}
+			if string(resp.Body) != body {
+				t.Errorf("Body = %q, want %q", resp.Body, body)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportUnsupportedEncoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write([]byte{0x28, 0xb5, 0x2f, 0xfd})
+	}))
+	defer srv.Close()
+
+	transport, err := newHTTPTransport(Settings{Transport: TransportHTTP})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.Do(&Request{Method: "GET", URL: srv.URL}); err == nil {
+		t.Fatal("Do() error = nil, want unsupported encoding error")
+	}
+}
+
+func TestCurlTransportMaxTime(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{30 * time.Second, "30"},
+		{1500 * time.Millisecond, "1.5"},
+		{250 * time.Millisecond, "0.25"},
+		{0, ""},
+	}
+
+	for _, tt := range tests {
+		transport := newCurlTransport(Settings{Timeout: tt.timeout})
+		args := transport.args(&Request{Method: "GET", URL: "https://claude.ai/api/x"})
+
+		got := ""
+		for i, arg := range args {
+			if arg == "--max-time" && i+1 < len(args) {
+				got = args[i+1]
+			}
+		}
+		if got != tt.want {
+			t.Errorf("timeout %s: --max-time = %q, want %q", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestCurlTransportSendsSecretsOnStdin(t *testing.T) {
+	curlPath, err := exec.LookPath("curl")
+	if err != nil {
+		t.Skip("curl not found")
+	}
+
+	const cookies = `sessionKey=abc; quoted="a\b"`
+	payload := []byte("{\"text\":\"line\\nnext\"}")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if r.Method != "POST" {
+			t.Errorf("Method = %s", r.Method)
+		}
+		if c := r.Header.Get("Cookie"); c != cookies {
+			t.Errorf("Cookie = %q, want %q", c, cookies)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != `Mozilla "test"` {
+			t.Errorf("User-Agent = %q", ua)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("Body = %q, want %q", got, payload)
+		}
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, "{}")
+	}))
+	defer srv.Close()
+
+	transport := newCurlTransport(Settings{CurlPath: curlPath})
+	resp, err := transport.Do(&Request{
+		Method:  "POST",
+		URL:     srv.URL,
+		Cookies: cookies,
+		Headers: map[string]string{"User-Agent": `Mozilla "test"`},
+		Body:    payload,
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "7" {
+		t.Errorf("response = %d %v", resp.StatusCode, resp.Header)
+	}
+}
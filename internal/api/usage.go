@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Well-known usage window names
+const (
+	WindowFiveHour = "five_hour"
+	WindowSevenDay = "seven_day"
+
+	// WindowAuto selects the most constrained window
+	WindowAuto = "auto"
+)
+
+// UsageWindow is a single limit window returned by the usage endpoint
+// (five_hour, seven_day, seven_day_opus, ...)
+type UsageWindow struct {
+	Name        string
+	Utilization float64
+	ResetsAt    *time.Time
+	Extra       map[string]json.RawMessage // Unknown window fields, preserved as-is
+}
+
+// UsageResponse represents the API response as a list of named windows
+type UsageResponse struct {
+	Windows  []UsageWindow
+	Extra    map[string]json.RawMessage // Top-level fields that aren't windows
+	selected string                     // Window used by GetInvertedValue, see SelectWindow
+}
+
+// UnmarshalJSON decodes every top-level object with a numeric "utilization" field as a window
+// A malformed window is logged and kept in Extra, so it doesn't break the others
+func (ur *UsageResponse) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	ur.Windows = nil
+	ur.Extra = make(map[string]json.RawMessage)
+
+	for name, raw := range fields {
+		window, ok, err := decodeWindow(name, raw)
+		if err != nil {
+			log.Printf("Skipping malformed usage window %q: %v", name, err)
+			ur.Extra[name] = raw
+			continue
+		}
+		if ok {
+			ur.Windows = append(ur.Windows, window)
+		} else {
+			ur.Extra[name] = raw
+		}
+	}
+
+	SortWindows(ur.Windows)
+	return nil
+}
+
+// MarshalJSON encodes the response back into the original API shape
+func (ur UsageResponse) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(ur.Windows)+len(ur.Extra))
+	for name, raw := range ur.Extra {
+		fields[name] = raw
+	}
+	for _, w := range ur.Windows {
+		window := make(map[string]interface{}, len(w.Extra)+2)
+		for key, raw := range w.Extra {
+			window[key] = raw
+		}
+		window["utilization"] = w.Utilization
+		window["resets_at"] = w.ResetsAt
+		fields[w.Name] = window
+	}
+	return json.Marshal(fields)
+}
+
+// decodeWindow decodes a single window object
+// Returns ok=false for values that aren't windows (null, scalars, objects without utilization)
+func decodeWindow(name string, raw json.RawMessage) (UsageWindow, bool, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return UsageWindow{}, false, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return UsageWindow{}, false, err
+	}
+
+	utilRaw, ok := fields["utilization"]
+	if !ok || bytes.Equal(bytes.TrimSpace(utilRaw), []byte("null")) {
+		return UsageWindow{}, false, nil
+	}
+
+	window := UsageWindow{Name: name}
+	if err := json.Unmarshal(utilRaw, &window.Utilization); err != nil {
+		return UsageWindow{}, false, fmt.Errorf("utilization: %w", err)
+	}
+	delete(fields, "utilization")
+
+	if resetsRaw, ok := fields["resets_at"]; ok {
+		if err := json.Unmarshal(resetsRaw, &window.ResetsAt); err != nil {
+			return UsageWindow{}, false, fmt.Errorf("resets_at: %w", err)
+		}
+		delete(fields, "resets_at")
+	}
+
+	if len(fields) > 0 {
+		window.Extra = fields
+	}
+	return window, true, nil
+}
+
+// SortWindows orders windows as the tooltip shows them: five_hour, seven_day, then the rest by name
+func SortWindows(windows []UsageWindow) {
+	rank := func(name string) int {
+		switch name {
+		case WindowFiveHour:
+			return 0
+		case WindowSevenDay:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		ri, rj := rank(windows[i].Name), rank(windows[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return windows[i].Name < windows[j].Name
+	})
+}
+
+// Remaining returns the inverted utilization value (100 - utilization) clamped to 0..100
+func (w *UsageWindow) Remaining() int {
+	remaining := 100 - w.Utilization
+	if remaining < 0 {
+		return 0
+	}
+	if remaining > 100 {
+		return 100
+	}
+	return int(remaining)
+}
+
+// resetDrift is how much ResetsAt may shift between polls within a single window period
+const resetDrift = time.Minute
+
+// ResetMoved returns true if two ResetsAt values of a window belong to different periods
+// Unknown (nil) reset times never count as a new period
+func ResetMoved(prev, next *time.Time) bool {
+	if prev == nil || next == nil {
+		return false
+	}
+	d := next.Sub(*prev)
+	if d < 0 {
+		d = -d
+	}
+	return d > resetDrift
+}
+
+// Label returns a short human-readable window name for tooltips
+func (w *UsageWindow) Label() string {
+	return WindowLabel(w.Name)
+}
+
+// WindowLabel returns a short human-readable label for a window name
+func WindowLabel(name string) string {
+	switch name {
+	case WindowFiveHour:
+		return "5ч"
+	case WindowSevenDay:
+		return "7д"
+	case "extra_usage":
+		return "Доп."
+	}
+
+	// seven_day_opus -> "7д Opus", seven_day_oauth_apps -> "7д Oauth Apps"
+	if strings.HasPrefix(name, WindowSevenDay+"_") {
+		return "7д " + titleWords(strings.TrimPrefix(name, WindowSevenDay+"_"))
+	}
+	if strings.HasPrefix(name, WindowFiveHour+"_") {
+		return "5ч " + titleWords(strings.TrimPrefix(name, WindowFiveHour+"_"))
+	}
+	return titleWords(name)
+}
+
+// titleWords converts snake_case into space separated capitalized words
+func titleWords(s string) string {
+	words := strings.Split(s, "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Window returns the window with the given name or nil
+func (ur *UsageResponse) Window(name string) *UsageWindow {
+	for i := range ur.Windows {
+		if ur.Windows[i].Name == name {
+			return &ur.Windows[i]
+		}
+	}
+	return nil
+}
+
+// MostConstrained returns the window with the highest utilization or nil if there are none
+func (ur *UsageResponse) MostConstrained() *UsageWindow {
+	var result *UsageWindow
+	for i := range ur.Windows {
+		if result == nil || ur.Windows[i].Utilization > result.Utilization {
+			result = &ur.Windows[i]
+		}
+	}
+	return result
+}
+
+// SelectWindow sets the window used by GetInvertedValue, GetResetTime and FormatTooltip
+// Use a window name (e.g. "five_hour") or "auto" for the most constrained window
+func (ur *UsageResponse) SelectWindow(name string) {
+	ur.selected = name
+}
+
+// PrimaryWindow returns the selected window
+// Falls back to the most constrained window if the selected one isn't present
+func (ur *UsageResponse) PrimaryWindow() *UsageWindow {
+	name := ur.selected
+	if name == "" {
+		name = WindowFiveHour
+	}
+	if name != WindowAuto {
+		if w := ur.Window(name); w != nil {
+			return w
+		}
+	}
+	return ur.MostConstrained()
+}
+
+// GetInvertedValue returns the inverted utilization value (100 - utilization) of the primary window
+func (ur *UsageResponse) GetInvertedValue() int {
+	w := ur.PrimaryWindow()
+	if w == nil {
+		return 100
+	}
+	return w.Remaining()
+}
+
+// GetResetTime returns the time when the primary window quota resets
+func (ur *UsageResponse) GetResetTime() *time.Time {
+	w := ur.PrimaryWindow()
+	if w == nil {
+		return nil
+	}
+	return w.ResetsAt
+}
+
+// GetSevenDayInvertedValue returns the inverted 7-day utilization value
+func (ur *UsageResponse) GetSevenDayInvertedValue() int {
+	w := ur.Window(WindowSevenDay)
+	if w == nil {
+		return 100
+	}
+	return w.Remaining()
+}
+
+// FormatResetTime formats reset time compactly: "15:04" within a day, "02.01 15:04" otherwise
+func FormatResetTime(t *time.Time) string {
+	if t == nil {
+		return "—"
+	}
+	if time.Until(*t) < 24*time.Hour {
+		return t.Local().Format("15:04")
+	}
+	return t.Local().Format("02.01 15:04")
+}
+
+// FormatTooltip creates a formatted tooltip string, stamped with the current time as the update time
+func (ur *UsageResponse) FormatTooltip() string {
+	lines := ur.TooltipLines()
+
+	// Add current timestamp for last update
+	currentTime := time.Now().Format("15:04 02.01")
+	lines = append(lines, "Обновлено: "+currentTime)
+
+	// Use \r\n for Windows multiline tooltips
+	return strings.Join(lines, "\r\n")
+}
+
+// TooltipLines formats the windows of the tooltip, two per line, without the update time
+func (ur *UsageResponse) TooltipLines() []string {
+	primary := ur.PrimaryWindow()
+
+	parts := make([]string, 0, len(ur.Windows))
+	for i := range ur.Windows {
+		w := &ur.Windows[i]
+		part := fmt.Sprintf("%s: %.0f%% (%s)", w.Label(), w.Utilization, FormatResetTime(w.ResetsAt))
+		// Mark the window shown on the icon when it isn't the usual five-hour one
+		if primary != nil && primary.Name == w.Name && w.Name != WindowFiveHour {
+			part = "▸" + part
+		}
+		parts = append(parts, part)
+	}
+
+	// Two windows per line to fit Windows tooltip limit (~63 chars per line)
+	var lines []string
+	for i := 0; i < len(parts); i += 2 {
+		end := i + 2
+		if end > len(parts) {
+			end = len(parts)
+		}
+		lines = append(lines, strings.Join(parts[i:end], " | "))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "Нет данных о лимитах")
+	}
+	return lines
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageResponseUnmarshal(t *testing.T) {
+	data := []byte(`{
+		"five_hour": {"utilization": 42.5, "resets_at": "2026-01-02T15:00:00Z"},
+		"seven_day_opus": {"utilization": 10, "resets_at": null, "tier": "max"},
+		"seven_day": null,
+		"extra_usage": {"is_enabled": false}
+	}`)
+
+	var usage UsageResponse
+	if err := json.Unmarshal(data, &usage); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(usage.Windows) != 2 {
+		t.Fatalf("got %d windows, want 2", len(usage.Windows))
+	}
+
+	five := usage.Window(WindowFiveHour)
+	if five == nil || five.Utilization != 42.5 || five.ResetsAt == nil || five.ResetsAt.Hour() != 15 {
+		t.Errorf("five_hour = %+v", five)
+	}
+	opus := usage.Window("seven_day_opus")
+	if opus == nil || opus.ResetsAt != nil || string(opus.Extra["tier"]) != `"max"` {
+		t.Errorf("seven_day_opus = %+v", opus)
+	}
+	for _, name := range []string{"seven_day", "extra_usage"} {
+		if _, ok := usage.Extra[name]; !ok {
+			t.Errorf("%s is missing from Extra", name)
+		}
+	}
+}
+
+func TestUsageResponseUnmarshalMalformedWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"non-numeric utilization", `{"five_hour": {"utilization": 30, "resets_at": null}, "seven_day_new": {"utilization": "high"}}`},
+		{"unexpected resets_at", `{"five_hour": {"utilization": 30, "resets_at": null}, "seven_day_new": {"utilization": 5, "resets_at": 1767366000}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var usage UsageResponse
+			if err := json.Unmarshal([]byte(tt.data), &usage); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if len(usage.Windows) != 1 || usage.Windows[0].Name != WindowFiveHour || usage.Windows[0].Utilization != 30 {
+				t.Fatalf("Windows = %+v, want only five_hour", usage.Windows)
+			}
+			if _, ok := usage.Extra["seven_day_new"]; !ok {
+				t.Error("malformed window is missing from Extra")
+			}
+
+			// The raw value survives a round trip
+			out, err := json.Marshal(usage)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(out, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := fields["seven_day_new"]; !ok {
+				t.Errorf("Marshal() lost the malformed window: %s", out)
+			}
+		})
+	}
+}
+
+func TestResetMoved(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	shift := func(d time.Duration) *time.Time {
+		t := at.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		prev *time.Time
+		next *time.Time
+		want bool
+	}{
+		{"same time", &at, shift(0), false},
+		{"drift within a minute", &at, shift(40 * time.Second), false},
+		{"exactly a minute", &at, shift(-time.Minute), false},
+		{"next period", &at, shift(5 * time.Hour), true},
+		{"moved back", &at, shift(-2 * time.Minute), true},
+		{"previous unknown", nil, &at, false},
+		{"next unknown", &at, nil, false},
+		{"both unknown", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := ResetMoved(tt.prev, tt.next); got != tt.want {
+			t.Errorf("%s: ResetMoved() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTooltipLines(t *testing.T) {
+	usage := &UsageResponse{Windows: []UsageWindow{
+		{Name: WindowFiveHour, Utilization: 58},
+		{Name: WindowSevenDay, Utilization: 29},
+		{Name: "seven_day_opus", Utilization: 10},
+	}}
+	usage.SelectWindow(WindowSevenDay)
+
+	want := []string{"5ч: 58% (—) | ▸7д: 29% (—)", "7д Opus: 10% (—)"}
+	got := usage.TooltipLines()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("TooltipLines() = %q, want %q", got, want)
+	}
+
+	// The tray tooltip adds the update time
+	if tooltip := usage.FormatTooltip(); !strings.HasPrefix(tooltip, want[0]+"\r\n"+want[1]+"\r\nОбновлено: ") {
+		t.Errorf("FormatTooltip() = %q", tooltip)
+	}
+
+	if got := (&UsageResponse{}).TooltipLines(); len(got) != 1 || got[0] != "Нет данных о лимитах" {
+		t.Errorf("TooltipLines() without windows = %q", got)
+	}
+}
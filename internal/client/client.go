@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"claudecompanion/internal/server"
+	"claudecompanion/internal/state"
+)
+
+// ErrNotRunning means nothing listens on the local API port
+var ErrNotRunning = errors.New("ClaudeCompanion is not running")
+
+// Client talks to the local API of a running instance
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New creates a client of the instance listening on 127.0.0.1:port
+// token is the pairing token, required only by Control
+func New(port int, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: fmt.Sprintf("http://127.0.0.1:%d", port),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Usage returns the current usage snapshot
+func (c *Client) Usage() (*state.Snapshot, error) {
+	var snap state.Snapshot
+	if err := c.get("/usage", &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// History returns samples, errors and notifications of the last hours
+func (c *Client) History(hours int) (*state.History, error) {
+	var history state.History
+	if err := c.get(fmt.Sprintf("/history?hours=%d", hours), &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// Follow calls onSnapshot with the current state and after every change
+// until the event stream ends; it always returns an error
+func (c *Client) Follow(onSnapshot func(*state.Snapshot)) error {
+	// The stream stays open, only connecting is limited by the timeout
+	stream := &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: c.http.Timeout}}
+	resp, err := stream.Get(c.baseURL + "/events")
+	if err != nil {
+		return fmt.Errorf("%w on %s", ErrNotRunning, c.baseURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	var eventType string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "":
+			// Notification events carry no snapshot
+			if data.Len() > 0 && eventType != state.EventNotification {
+				var event struct {
+					Data state.Snapshot `json:"data"`
+				}
+				if err := json.Unmarshal(data.Bytes(), &event); err == nil {
+					onSnapshot(&event.Data)
+				}
+			}
+			eventType = ""
+			data.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// Control performs a control action (server.ActionRefresh, ...)
+func (c *Client) Control(action string) error {
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/control/"+action, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(server.PairingHeader, c.token)
+	return c.do(req, nil)
+}
+
+// get fetches a JSON endpoint into out
+func (c *Client) get(path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, out)
+}
+
+// do sends a request and decodes the JSON response into out (if not nil)
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var netErr interface{ Timeout() bool }
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return fmt.Errorf("no response from %s: %w", c.baseURL, err)
+		}
+		return fmt.Errorf("%w on %s", ErrNotRunning, c.baseURL)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,18 +24,163 @@ type Config struct {
 	EnableFileFullLogging bool                  `yaml:"enable_file_full_logging"` // Log full cookies and curl commands
 	BrowserPath           string                `yaml:"browser_path"`
 	CurlPath              string                `yaml:"curl_path"` // Custom path to curl binary
+	Transport             string                `yaml:"transport"` // HTTP transport: "curl" or "http"
+	RequestTimeoutSeconds int                   `yaml:"request_timeout_seconds"`
+	UsageWindow           string                `yaml:"usage_window"` // Window shown on icon: "five_hour", "seven_day", ... or "auto"
 	LowValueNotifications LowValueNotifications `yaml:"low_value_notifications"`
+	RestoredNotifications RestoredNotifications `yaml:"restored_notifications"`
+	NotificationSinks     NotificationSinks     `yaml:"notification_sinks"`
 	DemoMode              DemoMode              `yaml:"demo_mode"`
 	Greeting              Greeting              `yaml:"greeting"`
 	WorkHours             WorkHours             `yaml:"work_hours"`
+	Scheduler             Scheduler             `yaml:"scheduler"`
+	Pairing               Pairing               `yaml:"pairing"`
+	History               History               `yaml:"history"`
+	Forecast              Forecast              `yaml:"forecast"`
+	MQTT                  MQTT                  `yaml:"mqtt"`
+	Hooks                 Hooks                 `yaml:"hooks"`
 	IconColors            IconColors            `yaml:"icon_colors"`
 }
 
 type LowValueNotifications struct {
-	Enabled     bool     `yaml:"enabled"`
-	Threshold   int      `yaml:"threshold"`
-	Phrases     []string `yaml:"phrases"`
-	ZeroPhrases []string `yaml:"zero_phrases"`
+	Enabled     bool                           `yaml:"enabled"`
+	Threshold   int                            `yaml:"threshold"`
+	Phrases     []string                       `yaml:"phrases"`
+	ZeroPhrases []string                       `yaml:"zero_phrases"`
+	Levels      []NotificationLevel            `yaml:"levels"`     // Ordered levels, replace threshold/phrases/zero_phrases when set
+	Hysteresis  *int                           `yaml:"hysteresis"` // Level is armed again after value rises N% above it, unset = 5
+	Windows     map[string]WindowNotifications `yaml:"windows"`    // Per-window settings by window name, e.g. "seven_day"
+}
+
+// WindowNotifications are low value notification settings of a single usage window
+type WindowNotifications struct {
+	Enabled     bool                `yaml:"enabled"`
+	Threshold   *int                `yaml:"threshold"` // 0 = only when exhausted, unset = top-level threshold
+	Phrases     []string            `yaml:"phrases"`
+	ZeroPhrases []string            `yaml:"zero_phrases"`
+	Levels      []NotificationLevel `yaml:"levels"`
+	Hysteresis  *int                `yaml:"hysteresis"` // Unset = top-level hysteresis
+}
+
+// NotificationLevel is one step of the low quota ladder
+type NotificationLevel struct {
+	Threshold int      `yaml:"threshold"` // Notify when remaining percent <= threshold (0 = exhausted)
+	Title     string   `yaml:"title"`
+	Phrases   []string `yaml:"phrases"`
+	Urgency   string   `yaml:"urgency"` // "low", "normal" or "critical"
+	Sound     string   `yaml:"sound"`   // Platform sound name, "" = default, "none" = silent
+}
+
+// ForWindow returns notification settings of a window
+// Windows listed in Windows use their own settings, the window shown on the icon
+// falls back to the top-level settings, other windows aren't watched
+func (l LowValueNotifications) ForWindow(name string, primary bool) (WindowNotifications, bool) {
+	if settings, ok := l.Windows[name]; ok {
+		return settings, settings.Enabled
+	}
+	if !primary {
+		return WindowNotifications{}, false
+	}
+	return WindowNotifications{
+		Enabled:     l.Enabled,
+		Threshold:   intPtr(l.Threshold),
+		Phrases:     l.Phrases,
+		ZeroPhrases: l.ZeroPhrases,
+		Levels:      l.Levels,
+		Hysteresis:  l.Hysteresis,
+	}, l.Enabled
+}
+
+// Ladder returns the levels sorted from the highest threshold to the lowest
+// Without levels, threshold/phrases and zero_phrases make a two-level ladder
+func (w WindowNotifications) Ladder() []NotificationLevel {
+	var levels []NotificationLevel
+	if len(w.Levels) > 0 {
+		levels = append(levels, w.Levels...)
+	} else {
+		if w.Threshold != nil && *w.Threshold > 0 {
+			levels = append(levels, NotificationLevel{Threshold: *w.Threshold, Phrases: w.Phrases})
+		}
+		levels = append(levels, NotificationLevel{Threshold: 0, Phrases: w.ZeroPhrases})
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Threshold > levels[j].Threshold
+	})
+	for i := range levels {
+		applyLevelDefaults(&levels[i])
+	}
+	return levels
+}
+
+// applyLevelDefaults fills title and urgency of a level
+func applyLevelDefaults(level *NotificationLevel) {
+	if level.Title == "" {
+		if level.Threshold <= 0 {
+			level.Title = "Квота исчерпана"
+		} else {
+			level.Title = "Низкая квота"
+		}
+	}
+	if level.Urgency == "" {
+		if level.Threshold <= 0 {
+			level.Urgency = "critical"
+		} else {
+			level.Urgency = "normal"
+		}
+	}
+}
+
+type RestoredNotifications struct {
+	Enabled   bool     `yaml:"enabled"`
+	OnlyAfter string   `yaml:"only_after"` // Notify only if the finished period reached: "any", "low" or "zero"
+	Phrases   []string `yaml:"phrases"`
+}
+
+// NotificationSinks configures where notifications are delivered
+type NotificationSinks struct {
+	Desktop  DesktopSink   `yaml:"desktop"`
+	Telegram TelegramSink  `yaml:"telegram"`
+	Webhooks []WebhookSink `yaml:"webhooks"`
+}
+
+// SinkFilter selects the notifications delivered to a sink
+type SinkFilter struct {
+	Types       []string `yaml:"types"`        // error, low, zero, forecast, restored, greeting; empty = all
+	MinSeverity string   `yaml:"min_severity"` // "info", "warning" or "critical"; empty = all
+}
+
+type DesktopSink struct {
+	Disabled *bool      `yaml:"disabled,omitempty"` // Don't show system notifications, unset = off in headless mode only
+	Filter   SinkFilter `yaml:",inline"`
+}
+
+type TelegramSink struct {
+	Enabled            bool              `yaml:"enabled"`
+	BotToken           string            `yaml:"bot_token"`
+	ChatIDs            []string          `yaml:"chat_ids"`
+	BaseURL            string            `yaml:"base_url"`             // Bot API URL, point to a local stand-in for testing
+	Proxy              string            `yaml:"proxy"`                // Proxy URL for the Bot API, empty = HTTP(S)_PROXY environment
+	ParseMode          string            `yaml:"parse_mode"`           // "", "HTML" or "MarkdownV2"
+	Template           string            `yaml:"template"`             // text/template of the message
+	Templates          map[string]string `yaml:"templates"`            // Templates by notification type, override template
+	MinIntervalSeconds int               `yaml:"min_interval_seconds"` // Minimum delay between messages
+	Filter             SinkFilter        `yaml:",inline"`
+}
+
+type WebhookSink struct {
+	Name                string            `yaml:"name"`
+	Disabled            bool              `yaml:"disabled"`
+	URL                 string            `yaml:"url"`
+	Method              string            `yaml:"method"`   // Default POST
+	Format              string            `yaml:"format"`   // "json" (default) or "form"
+	Template            string            `yaml:"template"` // text/template of the JSON body, empty = the event as JSON
+	Form                map[string]string `yaml:"form"`     // text/template of every form field, empty = all event fields
+	Headers             map[string]string `yaml:"headers"`
+	Secret              string            `yaml:"secret"`                // Sign the body with HMAC-SHA256, empty = unsigned
+	Retries             *int              `yaml:"retries"`               // Attempts after the first failed one, 0 = none, unset = 3
+	RetryBackoffSeconds int               `yaml:"retry_backoff_seconds"` // Delay before the first retry, doubled every time
+	Filter              SinkFilter        `yaml:",inline"`
 }
 
 type DemoMode struct {
@@ -54,6 +200,71 @@ type WorkHours struct {
 	End     string `yaml:"end"`   // Format: "20:00"
 }
 
+type Scheduler struct {
+	JitterPercent      *int    `yaml:"jitter_percent"`       // Randomize poll interval by ±N%, 0 = off, unset = 10
+	BackoffMaxSeconds  int     `yaml:"backoff_max_seconds"`  // Max delay between attempts after errors
+	Adaptive           *bool   `yaml:"adaptive"`             // Adjust interval to how fast usage changes, unset = true
+	MinIntervalSeconds int     `yaml:"min_interval_seconds"` // Lower bound for adaptive interval
+	MaxIntervalSeconds int     `yaml:"max_interval_seconds"` // Upper bound for adaptive interval, never below poll_interval_seconds
+	FastChangePercent  float64 `yaml:"fast_change_percent"`  // Poll twice as often when utilization changed by >= N% since last poll
+	FlatAfterMinutes   int     `yaml:"flat_after_minutes"`   // Double interval for every N minutes without change
+	ResetJitterSeconds int     `yaml:"reset_jitter_seconds"` // Random delay after reset when quota is exhausted
+}
+
+type Pairing struct {
+	Disabled     bool     `yaml:"disabled"`      // Accept context without pairing token (not recommended)
+	AllowedHosts []string `yaml:"allowed_hosts"` // Hosts accepted as targetUrl from extension
+}
+
+type History struct {
+	Disabled      bool `yaml:"disabled"`       // Don't write poll results to history.jsonl
+	RetentionDays int  `yaml:"retention_days"` // Remove records older than N days
+}
+
+type Forecast struct {
+	LookbackMinutes int  `yaml:"lookback_minutes"` // Burn rate is estimated from polls of the last N minutes
+	Notify          bool `yaml:"notify"`           // Notify when quota runs out before reset at the current pace
+	MinLeadMinutes  int  `yaml:"min_lead_minutes"` // Notify only if quota runs out at least N minutes before reset
+}
+
+type MQTT struct {
+	Enabled     bool          `yaml:"enabled"`
+	Broker      string        `yaml:"broker"` // tcp://host:1883, ssl://host:8883, ws://host:9001
+	ClientID    string        `yaml:"client_id"`
+	Username    string        `yaml:"username"`
+	Password    string        `yaml:"password"`
+	TopicPrefix string        `yaml:"topic_prefix"` // State topics: <prefix>/<window>/remaining, ...
+	Discovery   MQTTDiscovery `yaml:"discovery"`
+	TLS         MQTTTLS       `yaml:"tls"`
+}
+
+type MQTTDiscovery struct {
+	Disabled bool   `yaml:"disabled"` // Don't announce Home Assistant discovery configs
+	Prefix   string `yaml:"prefix"`   // Home Assistant discovery prefix
+}
+
+type MQTTTLS struct {
+	CAFile             string `yaml:"ca_file"`   // Broker CA in PEM, empty = system roots
+	CertFile           string `yaml:"cert_file"` // Client certificate for mutual TLS
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type Hooks struct {
+	MaxConcurrent  int    `yaml:"max_concurrent"`  // Commands running at the same time, others wait
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // Default timeout, the command is killed after it
+	Commands       []Hook `yaml:"commands"`
+}
+
+type Hook struct {
+	Name           string   `yaml:"name"`
+	Disabled       bool     `yaml:"disabled"`
+	Events         []string `yaml:"events"`  // low, zero, restored, error, greeting, forecast, context; empty = all
+	Command        string   `yaml:"command"` // Run by the system shell (sh -c, cmd /C)
+	Dir            string   `yaml:"dir"`     // Working directory
+	TimeoutSeconds int      `yaml:"timeout_seconds"`
+}
+
 type IconColors struct {
 	Green  ColorRGB `yaml:"green"`  // Color for >40% quota
 	Yellow ColorRGB `yaml:"yellow"` // Color for 20-40% quota
@@ -132,9 +343,31 @@ func (m *Manager) reload() error {
 		return err
 	}
 
+	config, err := parse(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.config = &config
+	callbacks := make([]func(*Config), len(m.onChange))
+	copy(callbacks, m.onChange)
+	m.mu.Unlock()
+
+	// Call callbacks
+	for _, callback := range callbacks {
+		callback(&config)
+	}
+
+	log.Println("Configuration reloaded successfully")
+	return nil
+}
+
+// parse decodes the configuration and applies defaults
+func parse(data []byte) (Config, error) {
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return err
+		return config, err
 	}
 
 	// Apply defaults
@@ -150,6 +383,115 @@ func (m *Manager) reload() error {
 	if config.NotificationThreshold == 0 {
 		config.NotificationThreshold = 10
 	}
+	if config.Transport == "" {
+		config.Transport = "curl"
+	}
+	if config.RequestTimeoutSeconds == 0 {
+		config.RequestTimeoutSeconds = 30
+	}
+	if config.UsageWindow == "" {
+		config.UsageWindow = "five_hour"
+	}
+	if config.Scheduler.JitterPercent == nil {
+		config.Scheduler.JitterPercent = intPtr(10)
+	}
+	if config.Scheduler.Adaptive == nil {
+		config.Scheduler.Adaptive = boolPtr(true)
+	}
+	if config.Scheduler.BackoffMaxSeconds == 0 {
+		config.Scheduler.BackoffMaxSeconds = 1800
+	}
+	if config.Scheduler.MinIntervalSeconds == 0 {
+		config.Scheduler.MinIntervalSeconds = 30
+	}
+	if config.Scheduler.MaxIntervalSeconds == 0 {
+		config.Scheduler.MaxIntervalSeconds = 600
+	}
+	if config.Scheduler.FastChangePercent == 0 {
+		config.Scheduler.FastChangePercent = 2
+	}
+	if config.Scheduler.FlatAfterMinutes == 0 {
+		config.Scheduler.FlatAfterMinutes = 30
+	}
+	if config.Scheduler.ResetJitterSeconds == 0 {
+		config.Scheduler.ResetJitterSeconds = 60
+	}
+	if config.LowValueNotifications.Hysteresis == nil {
+		config.LowValueNotifications.Hysteresis = intPtr(5)
+	}
+	// Configs written before per-window settings get the weekly warning too (windows: {} turns it off)
+	if config.LowValueNotifications.Windows == nil {
+		config.LowValueNotifications.Windows = defaultWindowNotifications(config.LowValueNotifications.Enabled)
+	}
+	// Per-window notifications inherit unset threshold, levels and phrases from the top level
+	for name, settings := range config.LowValueNotifications.Windows {
+		if len(settings.Levels) == 0 && settings.Threshold == nil {
+			settings.Levels = config.LowValueNotifications.Levels
+		}
+		if settings.Hysteresis == nil {
+			settings.Hysteresis = config.LowValueNotifications.Hysteresis
+		}
+		if settings.Threshold == nil {
+			settings.Threshold = intPtr(config.LowValueNotifications.Threshold)
+		}
+		if len(settings.Phrases) == 0 {
+			settings.Phrases = config.LowValueNotifications.Phrases
+		}
+		if len(settings.ZeroPhrases) == 0 {
+			settings.ZeroPhrases = config.LowValueNotifications.ZeroPhrases
+		}
+		config.LowValueNotifications.Windows[name] = settings
+	}
+	if len(config.Pairing.AllowedHosts) == 0 {
+		config.Pairing.AllowedHosts = []string{"claude.ai"}
+	}
+	if config.RestoredNotifications.OnlyAfter == "" {
+		config.RestoredNotifications.OnlyAfter = "low"
+	}
+	if config.NotificationSinks.Telegram.BaseURL == "" {
+		config.NotificationSinks.Telegram.BaseURL = "https://api.telegram.org"
+	}
+	if config.NotificationSinks.Telegram.MinIntervalSeconds == 0 {
+		config.NotificationSinks.Telegram.MinIntervalSeconds = 1
+	}
+	for i := range config.NotificationSinks.Webhooks {
+		webhook := &config.NotificationSinks.Webhooks[i]
+		if webhook.Retries == nil {
+			webhook.Retries = intPtr(3)
+		}
+		if webhook.RetryBackoffSeconds == 0 {
+			webhook.RetryBackoffSeconds = 2
+		}
+	}
+	if config.History.RetentionDays == 0 {
+		config.History.RetentionDays = 90
+	}
+	if config.Forecast.LookbackMinutes == 0 {
+		config.Forecast.LookbackMinutes = 60
+	}
+	if config.Forecast.MinLeadMinutes == 0 {
+		config.Forecast.MinLeadMinutes = 15
+	}
+	if config.MQTT.ClientID == "" {
+		config.MQTT.ClientID = "claudecompanion"
+	}
+	if config.MQTT.TopicPrefix == "" {
+		config.MQTT.TopicPrefix = "claudecompanion"
+	}
+	if config.MQTT.Discovery.Prefix == "" {
+		config.MQTT.Discovery.Prefix = "homeassistant"
+	}
+	if config.Hooks.MaxConcurrent == 0 {
+		config.Hooks.MaxConcurrent = 4
+	}
+	if config.Hooks.TimeoutSeconds == 0 {
+		config.Hooks.TimeoutSeconds = 30
+	}
+	for i := range config.Hooks.Commands {
+		if config.Hooks.Commands[i].TimeoutSeconds == 0 {
+			config.Hooks.Commands[i].TimeoutSeconds = config.Hooks.TimeoutSeconds
+		}
+	}
 	// Apply default icon colors if not set
 	if config.IconColors.Green.R == 0 && config.IconColors.Green.G == 0 && config.IconColors.Green.B == 0 {
 		config.IconColors.Green = ColorRGB{R: 0, G: 180, B: 0}
@@ -164,19 +506,7 @@ func (m *Manager) reload() error {
 		config.IconColors.Gray = ColorRGB{R: 128, G: 128, B: 128}
 	}
 
-	m.mu.Lock()
-	m.config = &config
-	callbacks := make([]func(*Config), len(m.onChange))
-	copy(callbacks, m.onChange)
-	m.mu.Unlock()
-
-	// Call callbacks
-	for _, callback := range callbacks {
-		callback(&config)
-	}
-
-	log.Println("Configuration reloaded successfully")
-	return nil
+	return config, nil
 }
 
 // watchChanges monitors the config file for changes
@@ -215,9 +545,13 @@ func (m *Manager) createDefaultConfig() error {
 		EnableFileLogging:     true,
 		EnableFileFullLogging: false, // Don't log full cookies/curl by default for security
 		BrowserPath:           "",
+		Transport:             "curl",
+		RequestTimeoutSeconds: 30,
+		UsageWindow:           "five_hour",
 		LowValueNotifications: LowValueNotifications{
-			Enabled:   true,
-			Threshold: 20,
+			Enabled:    true,
+			Threshold:  20,
+			Hysteresis: intPtr(5),
 			Phrases: []string{
 				"Пора идти домой! 🏡",
 				"Система устала. Вы — тоже. 😴",
@@ -230,6 +564,24 @@ func (m *Manager) createDefaultConfig() error {
 				"Game over! 🎮",
 				"Лимит исчерпан! 🚫",
 			},
+			Windows: defaultWindowNotifications(true),
+		},
+		RestoredNotifications: RestoredNotifications{
+			Enabled:   true,
+			OnlyAfter: "low",
+			Phrases: []string{
+				"С возвращением! 🚀",
+				"Лимит обновился — за работу! 💪",
+				"Токены снова в строю. ⚡",
+			},
+		},
+		NotificationSinks: NotificationSinks{
+			Telegram: TelegramSink{
+				Enabled:            false,
+				BaseURL:            "https://api.telegram.org",
+				Template:           "{{.Title}}\n{{.Message}}",
+				MinIntervalSeconds: 1,
+			},
 		},
 		DemoMode: DemoMode{
 			Enabled:         false,
@@ -245,6 +597,42 @@ func (m *Manager) createDefaultConfig() error {
 			Start:   "08:00", // 8 AM
 			End:     "20:00", // 8 PM
 		},
+		Scheduler: Scheduler{
+			JitterPercent:      intPtr(10),
+			BackoffMaxSeconds:  1800, // 30 minutes
+			Adaptive:           boolPtr(true),
+			MinIntervalSeconds: 30,
+			MaxIntervalSeconds: 600, // 10 minutes
+			FastChangePercent:  2,
+			FlatAfterMinutes:   30,
+			ResetJitterSeconds: 60,
+		},
+		Pairing: Pairing{
+			Disabled:     false,
+			AllowedHosts: []string{"claude.ai"},
+		},
+		History: History{
+			Disabled:      false,
+			RetentionDays: 90,
+		},
+		Forecast: Forecast{
+			LookbackMinutes: 60,
+			Notify:          true,
+			MinLeadMinutes:  15,
+		},
+		MQTT: MQTT{
+			Enabled:     false,
+			Broker:      "tcp://localhost:1883",
+			ClientID:    "claudecompanion",
+			TopicPrefix: "claudecompanion",
+			Discovery: MQTTDiscovery{
+				Prefix: "homeassistant",
+			},
+		},
+		Hooks: Hooks{
+			MaxConcurrent:  4,
+			TimeoutSeconds: 30,
+		},
 		IconColors: IconColors{
 			Green:  ColorRGB{R: 0, G: 180, B: 0},     // Green for >40%
 			Yellow: ColorRGB{R: 255, G: 165, B: 0},   // Yellow for 20-40%
@@ -261,6 +649,33 @@ func (m *Manager) createDefaultConfig() error {
 	return os.WriteFile(m.configPath, data, 0644)
 }
 
+// intPtr returns a pointer to v, for settings where 0 differs from unset
+// defaultWindowNotifications returns the weekly limit warning added to new and older configs
+func defaultWindowNotifications(enabled bool) map[string]WindowNotifications {
+	return map[string]WindowNotifications{
+		"seven_day": {
+			Enabled:   enabled,
+			Threshold: intPtr(20),
+			Phrases: []string{
+				"Недельный лимит на исходе — расходуйте с умом! 📅",
+				"До конца недели осталось немного. 🐢",
+			},
+			ZeroPhrases: []string{
+				"Недельный лимит исчерпан. До встречи после сброса! 🗓️",
+			},
+		},
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// boolPtr returns a pointer to v, for settings whose default is true
+func boolPtr(v bool) *bool {
+	return &v
+}
+
 // getConfigPath returns the config file path
 func getConfigPath() (string, error) {
 	// Get executable path
@@ -0,0 +1,139 @@
+package config
+
+import "testing"
+
+func TestParseWindowNotifications(t *testing.T) {
+	tests := []struct {
+		name          string
+		yaml          string
+		wantWindows   []string
+		wantEnabled   bool
+		wantThreshold int
+		wantLevels    []int // Ladder thresholds of seven_day
+	}{
+		{
+			name: "older config gets the weekly default",
+			yaml: `
+low_value_notifications:
+  enabled: true
+  threshold: 15
+`,
+			wantWindows:   []string{"seven_day"},
+			wantEnabled:   true,
+			wantThreshold: 20,
+			wantLevels:    []int{20, 0},
+		},
+		{
+			name: "weekly default follows the top-level switch",
+			yaml: `
+low_value_notifications:
+  enabled: false
+`,
+			wantWindows:   []string{"seven_day"},
+			wantThreshold: 20,
+			wantLevels:    []int{20, 0},
+		},
+		{
+			name: "empty windows turn the default off",
+			yaml: `
+low_value_notifications:
+  enabled: true
+  windows: {}
+`,
+		},
+		{
+			name: "threshold 0 notifies only when exhausted",
+			yaml: `
+low_value_notifications:
+  enabled: true
+  threshold: 30
+  windows:
+    seven_day:
+      enabled: true
+      threshold: 0
+`,
+			wantWindows: []string{"seven_day"},
+			wantEnabled: true,
+			wantLevels:  []int{0},
+		},
+		{
+			name: "unset threshold is inherited",
+			yaml: `
+low_value_notifications:
+  enabled: true
+  threshold: 30
+  windows:
+    seven_day:
+      enabled: true
+`,
+			wantWindows:   []string{"seven_day"},
+			wantEnabled:   true,
+			wantThreshold: 30,
+			wantLevels:    []int{30, 0},
+		},
+		{
+			name: "unset threshold inherits levels",
+			yaml: `
+low_value_notifications:
+  enabled: true
+  levels:
+    - threshold: 50
+    - threshold: 5
+  windows:
+    seven_day:
+      enabled: true
+`,
+			wantWindows: []string{"seven_day"},
+			wantEnabled: true,
+			wantLevels:  []int{50, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			windows := cfg.LowValueNotifications.Windows
+			if len(windows) != len(tt.wantWindows) {
+				t.Fatalf("Windows = %v, want %v", windows, tt.wantWindows)
+			}
+			if len(tt.wantWindows) == 0 {
+				return
+			}
+
+			settings, enabled := cfg.LowValueNotifications.ForWindow("seven_day", false)
+			if enabled != tt.wantEnabled {
+				t.Errorf("enabled = %v, want %v", enabled, tt.wantEnabled)
+			}
+			if settings.Threshold == nil || *settings.Threshold != tt.wantThreshold {
+				t.Errorf("Threshold = %v, want %d", settings.Threshold, tt.wantThreshold)
+			}
+			ladder := settings.Ladder()
+			if len(ladder) != len(tt.wantLevels) {
+				t.Fatalf("Ladder() = %+v, want thresholds %v", ladder, tt.wantLevels)
+			}
+			for i, level := range ladder {
+				if level.Threshold != tt.wantLevels[i] {
+					t.Errorf("Ladder()[%d].Threshold = %d, want %d", i, level.Threshold, tt.wantLevels[i])
+				}
+			}
+		})
+	}
+}
+
+func TestForWindowPrimaryFallback(t *testing.T) {
+	cfg, err := parse([]byte("low_value_notifications:\n  enabled: true\n  threshold: 0\n  windows: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, enabled := cfg.LowValueNotifications.ForWindow("five_hour", true)
+	if !enabled || settings.Threshold == nil || *settings.Threshold != 0 {
+		t.Errorf("ForWindow(primary) = %+v, %v", settings, enabled)
+	}
+	if _, enabled := cfg.LowValueNotifications.ForWindow("seven_day_opus", false); enabled {
+		t.Error("unlisted window is watched")
+	}
+}
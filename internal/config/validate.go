@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration once, without creating or watching the file
+// A missing file yields the defaults. Used by CLI commands that talk to a running instance
+func Load() (Config, string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return Config{}, "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return Config{}, path, err
+	}
+	cfg, err := parse(data)
+	return cfg, path, err
+}
+
+// Path returns the configuration file path
+func Path() (string, error) {
+	return getConfigPath()
+}
+
+// Validate checks a configuration file for syntax errors, unknown keys and invalid values
+// Returns the list of problems, empty if the file is valid
+func Validate(data []byte) []string {
+	var problems []string
+
+	// Unknown keys are usually typos that silently fall back to defaults
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var strict Config
+	if err := decoder.Decode(&strict); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []string{err.Error()}
+		}
+		problems = append(problems, typeErr.Errors...)
+	}
+
+	cfg, err := parse(data)
+	if err != nil {
+		// Type errors were already reported by the strict decoder
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return problems
+		}
+		return append(problems, err.Error())
+	}
+	return append(problems, cfg.check()...)
+}
+
+// check returns problems with values that can't be detected by decoding
+func (c *Config) check() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.ServerPort < 1 || c.ServerPort > 65535 {
+		add("server_port: %d is not a valid port", c.ServerPort)
+	}
+	if c.Transport != "curl" && c.Transport != "http" {
+		add("transport: %q must be curl or http", c.Transport)
+	}
+	if c.Proxy != "" {
+		if _, err := url.Parse(c.Proxy); err != nil {
+			add("proxy: %v", err)
+		}
+	}
+	if c.WorkHours.Enabled {
+		for key, value := range map[string]string{"start": c.WorkHours.Start, "end": c.WorkHours.End} {
+			if _, err := time.Parse("15:04", value); err != nil {
+				add("work_hours.%s: %q must be HH:MM", key, value)
+			}
+		}
+	}
+
+	checkLevels := func(prefix string, levels []NotificationLevel) {
+		for i, level := range levels {
+			switch level.Urgency {
+			case "", "low", "normal", "critical":
+			default:
+				add("%s.levels[%d].urgency: %q must be low, normal or critical", prefix, i, level.Urgency)
+			}
+			if level.Threshold < 0 || level.Threshold > 100 {
+				add("%s.levels[%d].threshold: %d must be 0-100", prefix, i, level.Threshold)
+			}
+		}
+	}
+	checkLevels("low_value_notifications", c.LowValueNotifications.Levels)
+	for name, window := range c.LowValueNotifications.Windows {
+		checkLevels("low_value_notifications.windows."+name, window.Levels)
+	}
+
+	switch c.RestoredNotifications.OnlyAfter {
+	case "any", "low", "zero":
+	default:
+		add("restored_notifications.only_after: %q must be any, low or zero", c.RestoredNotifications.OnlyAfter)
+	}
+
+	checkFilter := func(prefix string, filter SinkFilter) {
+		switch strings.ToLower(filter.MinSeverity) {
+		case "", "info", "warning", "critical":
+		default:
+			add("%s.min_severity: %q must be info, warning or critical", prefix, filter.MinSeverity)
+		}
+	}
+	checkTemplate := func(key, text string) {
+		if text == "" {
+			return
+		}
+		// Functions are registered by the sinks, here only the syntax is checked
+		funcs := template.FuncMap{"escape": strings.TrimSpace, "json": strings.TrimSpace}
+		if _, err := template.New(key).Funcs(funcs).Parse(text); err != nil {
+			add("%s: %v", key, err)
+		}
+	}
+
+	sinks := c.NotificationSinks
+	checkFilter("notification_sinks.desktop", sinks.Desktop.Filter)
+	if sinks.Telegram.Enabled {
+		if sinks.Telegram.BotToken == "" {
+			add("notification_sinks.telegram.bot_token is required")
+		}
+		if len(sinks.Telegram.ChatIDs) == 0 {
+			add("notification_sinks.telegram.chat_ids is empty")
+		}
+	}
+	checkFilter("notification_sinks.telegram", sinks.Telegram.Filter)
+	checkTemplate("notification_sinks.telegram.template", sinks.Telegram.Template)
+	for eventType, text := range sinks.Telegram.Templates {
+		checkTemplate("notification_sinks.telegram.templates."+eventType, text)
+	}
+	for i, webhook := range sinks.Webhooks {
+		prefix := fmt.Sprintf("notification_sinks.webhooks[%d]", i)
+		if _, err := url.ParseRequestURI(webhook.URL); err != nil {
+			add("%s.url: %q is not a valid URL", prefix, webhook.URL)
+		}
+		switch webhook.Format {
+		case "", "json", "form":
+		default:
+			add("%s.format: %q must be json or form", prefix, webhook.Format)
+		}
+		checkFilter(prefix, webhook.Filter)
+		checkTemplate(prefix+".template", webhook.Template)
+		for field, text := range webhook.Form {
+			checkTemplate(prefix+".form."+field, text)
+		}
+	}
+
+	if c.MQTT.Enabled && c.MQTT.Broker == "" {
+		add("mqtt.broker is required")
+	}
+	for i, hook := range c.Hooks.Commands {
+		if hook.Command == "" {
+			add("hooks.commands[%d].command is required", i)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,160 @@
+package forecast
+
+import (
+	"sync"
+	"time"
+
+	"claudecompanion/internal/api"
+)
+
+const (
+	// minSpan is the shortest sample span a burn rate is estimated from
+	minSpan = 5 * time.Minute
+	// minBurnRate is the rate (percent per hour) below which usage is considered flat
+	minBurnRate = 0.1
+)
+
+// Forecast is the projected exhaustion of a single usage window
+type Forecast struct {
+	Window      string     `json:"window"`
+	BurnRate    float64    `json:"burn_rate"`    // Utilization percent per hour
+	ExhaustsAt  *time.Time `json:"exhausts_at"`  // nil if usage isn't growing
+	ResetsAt    *time.Time `json:"resets_at"`    // nil if unknown
+	BeforeReset bool       `json:"before_reset"` // Quota runs out before the window resets
+}
+
+// Lead returns how long before the reset the quota runs out (0 if it doesn't)
+func (f *Forecast) Lead() time.Duration {
+	if !f.BeforeReset {
+		return 0
+	}
+	return f.ResetsAt.Sub(*f.ExhaustsAt)
+}
+
+// TooltipLine formats the tooltip line: "Кончится в 15:40, за 1ч20м до сброса"
+func (f *Forecast) TooltipLine() string {
+	return "Кончится в " + api.FormatResetTime(f.ExhaustsAt) + ", за " + api.FormatDuration(f.Lead()) + " до сброса"
+}
+
+// point is a single utilization sample
+type point struct {
+	at          time.Time
+	utilization float64
+	resetsAt    *time.Time
+}
+
+// Estimator keeps recent samples of every window and projects when each runs out
+type Estimator struct {
+	mu       sync.Mutex
+	lookback time.Duration
+	series   map[string][]point
+}
+
+// NewEstimator creates an estimator using samples from the last lookback duration
+func NewEstimator(lookback time.Duration) *Estimator {
+	return &Estimator{
+		lookback: lookback,
+		series:   make(map[string][]point),
+	}
+}
+
+// SetLookback changes how far back samples are used
+func (e *Estimator) SetLookback(lookback time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lookback = lookback
+}
+
+// AddUsage adds a sample of every window from a successful poll
+func (e *Estimator) AddUsage(usage *api.UsageResponse, at time.Time) {
+	for _, w := range usage.Windows {
+		e.Add(w.Name, w.Utilization, w.ResetsAt, at)
+	}
+}
+
+// Add adds a single window sample
+// Samples from a previous period (window reset) are discarded
+func (e *Estimator) Add(window string, utilization float64, resetsAt *time.Time, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	series := e.series[window]
+	if n := len(series); n > 0 {
+		last := series[n-1]
+		if !at.After(last.at) {
+			return
+		}
+		if utilization < last.utilization || api.ResetMoved(last.resetsAt, resetsAt) {
+			series = nil
+		}
+	}
+	series = append(series, point{at: at, utilization: utilization, resetsAt: resetsAt})
+
+	// Drop samples outside the lookback
+	cutoff := at.Add(-e.lookback)
+	drop := 0
+	for drop < len(series)-1 && series[drop].at.Before(cutoff) {
+		drop++
+	}
+	e.series[window] = append([]point(nil), series[drop:]...)
+}
+
+// Forecasts returns forecasts for the windows of usage in the same order
+// Windows without enough samples are skipped
+func (e *Estimator) Forecasts(usage *api.UsageResponse) []Forecast {
+	forecasts := make([]Forecast, 0, len(usage.Windows))
+	for _, w := range usage.Windows {
+		if f := e.Forecast(w.Name); f != nil {
+			forecasts = append(forecasts, *f)
+		}
+	}
+	return forecasts
+}
+
+// Forecast returns the forecast for a window, nil if there isn't enough data
+func (e *Estimator) Forecast(window string) *Forecast {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	series := e.series[window]
+	if len(series) < 2 || series[len(series)-1].at.Sub(series[0].at) < minSpan {
+		return nil
+	}
+
+	last := series[len(series)-1]
+	f := &Forecast{
+		Window:   window,
+		BurnRate: burnRate(series),
+		ResetsAt: last.resetsAt,
+	}
+
+	if f.BurnRate >= minBurnRate {
+		left := 100 - last.utilization
+		if left < 0 {
+			left = 0
+		}
+		exhaustsAt := last.at.Add(time.Duration(left / f.BurnRate * float64(time.Hour)))
+		f.ExhaustsAt = &exhaustsAt
+		f.BeforeReset = f.ResetsAt != nil && exhaustsAt.Before(*f.ResetsAt)
+	}
+	return f
+}
+
+// burnRate fits utilization over time with least squares and returns the slope in percent per hour
+func burnRate(series []point) float64 {
+	origin := series[0].at
+	n := float64(len(series))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range series {
+		x := p.at.Sub(origin).Hours()
+		sumX += x
+		sumY += p.utilization
+		sumXY += x * p.utilization
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
@@ -0,0 +1,202 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"claudecompanion/internal/api"
+)
+
+var start = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+// sample is a utilization value minutes after start
+type sample struct {
+	minutes     int
+	utilization float64
+}
+
+func TestBurnRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []sample
+		want    float64
+	}{
+		{"linear", []sample{{0, 10}, {30, 20}, {60, 30}}, 20},
+		{"flat", []sample{{0, 40}, {30, 40}, {60, 40}}, 0},
+		{"noisy fit", []sample{{0, 10}, {20, 15}, {40, 16}, {60, 22}}, 11.1},
+		{"same time", []sample{{0, 10}, {0, 20}}, 0},
+		{"two points", []sample{{0, 50}, {15, 55}}, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := make([]point, len(tt.samples))
+			for i, s := range tt.samples {
+				series[i] = point{at: start.Add(time.Duration(s.minutes) * time.Minute), utilization: s.utilization}
+			}
+			if got := burnRate(series); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("burnRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForecast(t *testing.T) {
+	resetsAt := start.Add(4 * time.Hour)
+	laterReset := start.Add(8 * time.Hour)
+
+	tests := []struct {
+		name            string
+		samples         []sample
+		resetsAt        *time.Time
+		wantNil         bool
+		wantRate        float64
+		wantExhaustsIn  time.Duration // After the last sample, 0 = no exhaustion
+		wantBeforeReset bool
+	}{
+		{name: "no samples", wantNil: true},
+		{name: "single sample", samples: []sample{{0, 10}}, resetsAt: &resetsAt, wantNil: true},
+		{name: "span below minimum", samples: []sample{{0, 10}, {2, 12}, {4, 14}}, resetsAt: &resetsAt, wantNil: true},
+		{
+			name:            "runs out before reset",
+			samples:         []sample{{0, 40}, {30, 50}, {60, 60}},
+			resetsAt:        &resetsAt,
+			wantRate:        20,
+			wantExhaustsIn:  2 * time.Hour,
+			wantBeforeReset: true,
+		},
+		{
+			name:           "runs out after reset",
+			samples:        []sample{{0, 10}, {60, 15}},
+			resetsAt:       &resetsAt,
+			wantRate:       5,
+			wantExhaustsIn: 17 * time.Hour,
+		},
+		{
+			name:           "unknown reset",
+			samples:        []sample{{0, 40}, {30, 50}, {60, 60}},
+			wantRate:       20,
+			wantExhaustsIn: 2 * time.Hour,
+		},
+		{name: "flat usage", samples: []sample{{0, 30}, {30, 30}, {60, 30}}, resetsAt: &resetsAt},
+		{name: "slower than minimum rate", samples: []sample{{0, 30}, {60, 30.05}}, resetsAt: &resetsAt, wantRate: 0.05},
+		{
+			name:            "already exhausted",
+			samples:         []sample{{0, 80}, {30, 90}, {60, 100}},
+			resetsAt:        &laterReset,
+			wantRate:        20,
+			wantExhaustsIn:  time.Nanosecond, // Exhausted at the last sample
+			wantBeforeReset: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEstimator(2 * time.Hour)
+			var last time.Time
+			for _, s := range tt.samples {
+				last = start.Add(time.Duration(s.minutes) * time.Minute)
+				e.Add(api.WindowFiveHour, s.utilization, tt.resetsAt, last)
+			}
+
+			f := e.Forecast(api.WindowFiveHour)
+			if tt.wantNil {
+				if f != nil {
+					t.Fatalf("Forecast() = %+v, want nil", f)
+				}
+				return
+			}
+			if f == nil {
+				t.Fatal("Forecast() = nil")
+			}
+			if math.Abs(f.BurnRate-tt.wantRate) > 1e-9 {
+				t.Errorf("BurnRate = %v, want %v", f.BurnRate, tt.wantRate)
+			}
+			switch {
+			case tt.wantExhaustsIn == 0 && f.ExhaustsAt != nil:
+				t.Errorf("ExhaustsAt = %s, want nil", f.ExhaustsAt)
+			case tt.wantExhaustsIn == time.Nanosecond && (f.ExhaustsAt == nil || !f.ExhaustsAt.Equal(last)):
+				t.Errorf("ExhaustsAt = %v, want %s", f.ExhaustsAt, last)
+			case tt.wantExhaustsIn > time.Nanosecond && (f.ExhaustsAt == nil || !f.ExhaustsAt.Equal(last.Add(tt.wantExhaustsIn))):
+				t.Errorf("ExhaustsAt = %v, want %s", f.ExhaustsAt, last.Add(tt.wantExhaustsIn))
+			}
+			if f.BeforeReset != tt.wantBeforeReset {
+				t.Errorf("BeforeReset = %v, want %v", f.BeforeReset, tt.wantBeforeReset)
+			}
+			if tt.wantBeforeReset && f.Lead() != f.ResetsAt.Sub(*f.ExhaustsAt) {
+				t.Errorf("Lead() = %s", f.Lead())
+			} else if !tt.wantBeforeReset && f.Lead() != 0 {
+				t.Errorf("Lead() = %s, want 0", f.Lead())
+			}
+		})
+	}
+}
+
+func TestAddDiscardsPreviousPeriod(t *testing.T) {
+	resetsAt := start.Add(time.Hour)
+	nextReset := start.Add(6 * time.Hour)
+	drifted := resetsAt.Add(30 * time.Second)
+
+	tests := []struct {
+		name     string
+		next     float64
+		resetsAt *time.Time
+		want     int // Samples kept after adding the third sample
+	}{
+		{"same period", 30, &resetsAt, 3},
+		{"reset time drifted within tolerance", 30, &drifted, 3},
+		{"utilization dropped", 5, &resetsAt, 1},
+		{"reset time moved", 30, &nextReset, 1},
+		{"reset time unknown", 30, nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEstimator(5 * time.Hour)
+			e.Add(api.WindowFiveHour, 10, &resetsAt, start)
+			e.Add(api.WindowFiveHour, 20, &resetsAt, start.Add(10*time.Minute))
+			e.Add(api.WindowFiveHour, tt.next, tt.resetsAt, start.Add(20*time.Minute))
+
+			if got := len(e.series[api.WindowFiveHour]); got != tt.want {
+				t.Errorf("kept %d samples, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddLookbackAndOrdering(t *testing.T) {
+	e := NewEstimator(30 * time.Minute)
+	for i := 0; i <= 6; i++ {
+		e.Add(api.WindowFiveHour, float64(10+i), nil, start.Add(time.Duration(i)*10*time.Minute))
+	}
+	// Out-of-order and duplicate samples are ignored
+	e.Add(api.WindowFiveHour, 99, nil, start.Add(30*time.Minute))
+	e.Add(api.WindowFiveHour, 99, nil, start.Add(60*time.Minute))
+
+	series := e.series[api.WindowFiveHour]
+	if len(series) != 4 || !series[0].at.Equal(start.Add(30*time.Minute)) || series[len(series)-1].utilization != 16 {
+		t.Errorf("series = %+v, want the last 30 minutes", series)
+	}
+}
+
+func TestForecastsKeepsWindowOrder(t *testing.T) {
+	e := NewEstimator(time.Hour)
+	usage := func(five, week float64) *api.UsageResponse {
+		return &api.UsageResponse{Windows: []api.UsageWindow{
+			{Name: api.WindowFiveHour, Utilization: five},
+			{Name: "seven_day", Utilization: week},
+			{Name: "seven_day_opus", Utilization: 1},
+		}}
+	}
+	e.AddUsage(usage(10, 50), start)
+	e.AddUsage(usage(20, 51), start.Add(30*time.Minute))
+
+	forecasts := e.Forecasts(usage(20, 51))
+	if len(forecasts) != 3 || forecasts[0].Window != api.WindowFiveHour || forecasts[1].Window != "seven_day" {
+		t.Fatalf("Forecasts() = %+v", forecasts)
+	}
+	if forecasts[0].BurnRate != 20 || forecasts[1].BurnRate != 2 || forecasts[2].ExhaustsAt != nil {
+		t.Errorf("Forecasts() = %+v", forecasts)
+	}
+}
@@ -0,0 +1,282 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"claudecompanion/internal/api"
+)
+
+// FileName is the history file name, stored next to config.yaml
+const FileName = "history.jsonl"
+
+// compactInterval is how often records older than the retention are removed from the file
+const compactInterval = 24 * time.Hour
+
+// Window is the state of a single usage window at poll time
+type Window struct {
+	Name        string     `json:"name"`
+	Utilization float64    `json:"utilization"`
+	ResetsAt    *time.Time `json:"resets_at,omitempty"`
+}
+
+// Error describes a failed poll
+type Error struct {
+	Kind       string `json:"kind"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Record is one poll result: either Windows (success) or Error (failure)
+type Record struct {
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile,omitempty"` // Organization ID the usage belongs to
+	Windows []Window  `json:"windows,omitempty"`
+	Error   *Error    `json:"error,omitempty"`
+}
+
+// IsError returns true for failed polls
+func (r *Record) IsError() bool {
+	return r.Error != nil
+}
+
+// Store is an append-only JSONL file of poll results
+// Records older than the retention are dropped when the file is compacted
+type Store struct {
+	mu          sync.Mutex
+	path        string
+	retention   time.Duration
+	file        *os.File
+	lastCompact time.Time
+}
+
+// Open opens (or creates) the history file and compacts it
+// retention <= 0 keeps records forever
+func Open(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s := &Store{path: path, retention: retention}
+	if err := s.compact(time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.openFile(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetRetention changes the retention, applied on the next compaction
+func (s *Store) SetRetention(retention time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = retention
+}
+
+// AppendUsage records a successful poll
+func (s *Store) AppendUsage(profile string, usage *api.UsageResponse, at time.Time) error {
+	windows := make([]Window, 0, len(usage.Windows))
+	for _, w := range usage.Windows {
+		windows = append(windows, Window{
+			Name:        w.Name,
+			Utilization: w.Utilization,
+			ResetsAt:    w.ResetsAt,
+		})
+	}
+	return s.Append(Record{Time: at, Profile: profile, Windows: windows})
+}
+
+// AppendError records a failed poll
+func (s *Store) AppendError(profile string, fetchErr *api.FetchError, at time.Time) error {
+	return s.Append(Record{
+		Time:    at,
+		Profile: profile,
+		Error: &Error{
+			Kind:       fetchErr.Kind.String(),
+			StatusCode: fetchErr.StatusCode,
+			Message:    fetchErr.Description(),
+		},
+	})
+}
+
+// Append writes a record to the end of the file
+func (s *Store) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("history store is closed")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history record: %w", err)
+	}
+
+	if time.Since(s.lastCompact) >= compactInterval {
+		if err := s.recompact(); err != nil {
+			log.Printf("History compaction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// Query returns records with Time >= since, oldest first
+func (s *Store) Query(since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	_, err := s.scan(func(rec Record) {
+		if !rec.Time.Before(since) {
+			records = append(records, rec)
+		}
+	})
+	return records, err
+}
+
+// Close closes the history file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// openFile opens the history file for appending (caller holds s.mu or owns s)
+func (s *Store) openFile() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	// Terminate a line cut off by a crash so the next record starts on its own line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte("\n"))
+		}
+	}
+
+	s.file = file
+	return nil
+}
+
+// recompact closes the file, compacts it and opens it again (caller holds s.mu)
+// The file has to be closed first because Windows can't replace an open file
+func (s *Store) recompact() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	compactErr := s.compact(time.Now())
+	if err := s.openFile(); err != nil {
+		return err
+	}
+	return compactErr
+}
+
+// compact rewrites the file without expired and unreadable records (caller holds s.mu or owns s)
+func (s *Store) compact(now time.Time) error {
+	s.lastCompact = now
+	if s.retention <= 0 {
+		return nil
+	}
+
+	cutoff := now.Add(-s.retention)
+	var kept []Record
+	dropped := 0
+	skipped, err := s.scan(func(rec Record) {
+		if rec.Time.Before(cutoff) {
+			dropped++
+			return
+		}
+		kept = append(kept, rec)
+	})
+	if err != nil {
+		return err
+	}
+	if dropped == 0 && skipped == 0 {
+		return nil
+	}
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create history file: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, rec := range kept {
+		if err := enc.Encode(rec); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write history file: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace history file: %w", err)
+	}
+
+	log.Printf("History compacted: %d records removed, %d unreadable lines skipped, %d kept", dropped, skipped, len(kept))
+	return nil
+}
+
+// scan calls fn for every readable record in the file and returns the number of skipped lines
+// Lines that can't be parsed (e.g. cut off by a crash) are skipped
+func (s *Store) scan(fn func(Record)) (int, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	skipped := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			skipped++
+			continue
+		}
+		fn(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return skipped, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return skipped, nil
+}
@@ -0,0 +1,199 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"claudecompanion/internal/api"
+)
+
+// writeLines creates a history file from raw lines, the last one without a trailing newline
+func writeLines(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func recordLine(t *testing.T, at time.Time, utilization float64) string {
+	data, err := json.Marshal(Record{Time: at, Profile: "org", Windows: []Window{{Name: api.WindowFiveHour, Utilization: utilization}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// readLines returns the non-empty lines of the file and fails on any line that is not a record
+func readLines(t *testing.T, path string) []Record {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		t.Errorf("file does not end with a newline: %q", data)
+	}
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Errorf("unreadable line %q: %v", line, err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func utilizations(records []Record) []float64 {
+	var out []float64
+	for _, rec := range records {
+		if len(rec.Windows) > 0 {
+			out = append(out, rec.Windows[0].Utilization)
+		}
+	}
+	return out
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOpenCompactsExpiredAndPartialRecords(t *testing.T) {
+	now := time.Now()
+	path := writeLines(t,
+		recordLine(t, now.Add(-10*24*time.Hour), 1),
+		recordLine(t, now.Add(-8*24*time.Hour), 2),
+		`not json`,
+		recordLine(t, now.Add(-2*24*time.Hour), 3),
+		recordLine(t, now.Add(-time.Hour), 4),
+		`{"time":"2026-01-02T10:00:00Z","windows":[{"name":"five_h`, // Cut off by a crash
+	)
+
+	store, err := Open(path, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	if got := utilizations(readLines(t, path)); !equalFloats(got, []float64{3, 4}) {
+		t.Errorf("compacted file = %v, want [3 4]", got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := store.AppendUsage("org", &api.UsageResponse{Windows: []api.UsageWindow{{Name: api.WindowFiveHour, Utilization: 5}}}, now); err != nil {
+		t.Fatalf("AppendUsage() error = %v", err)
+	}
+	records, err := store.Query(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := utilizations(records); !equalFloats(got, []float64{4, 5}) {
+		t.Errorf("Query() = %v, want [4 5]", got)
+	}
+}
+
+func TestOpenDropsPartialLineWhenNothingExpired(t *testing.T) {
+	now := time.Now()
+	path := writeLines(t,
+		recordLine(t, now.Add(-time.Hour), 1),
+		`{"time":"2026-01-02T10:00:00Z","win`,
+	)
+
+	store, err := Open(path, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	if got := utilizations(readLines(t, path)); !equalFloats(got, []float64{1}) {
+		t.Errorf("compacted file = %v, want [1]", got)
+	}
+}
+
+func TestOpenTerminatesPartialLineWithoutRetention(t *testing.T) {
+	now := time.Now()
+	path := writeLines(t,
+		recordLine(t, now.Add(-400*24*time.Hour), 1),
+		`{"time":"2026-01-02T10:00:00Z","win`,
+	)
+
+	store, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	fetchErr := &api.FetchError{Kind: api.ErrorServer, StatusCode: 502}
+	if err := store.AppendError("org", fetchErr, now); err != nil {
+		t.Fatalf("AppendError() error = %v", err)
+	}
+
+	// Nothing expires, the partial line stays but the new record starts on its own line
+	records, err := store.Query(time.Time{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(records) != 2 || records[0].IsError() || !records[1].IsError() || records[1].Error.StatusCode != 502 {
+		t.Fatalf("Query() = %+v, want the old record and the error", records)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 3 {
+		t.Errorf("file has %d lines, want 3:\n%s", len(lines), data)
+	}
+}
+
+func TestAppendRecompactsPeriodically(t *testing.T) {
+	now := time.Now()
+	path := writeLines(t, recordLine(t, now.Add(-time.Hour), 1))
+
+	store, err := Open(path, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	// Records that expired while the app was running are removed on the next daily compaction
+	store.SetRetention(30 * time.Minute)
+	store.lastCompact = now.Add(-compactInterval)
+	if err := store.Append(Record{Time: now, Windows: []Window{{Name: api.WindowFiveHour, Utilization: 2}}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	if got := utilizations(readLines(t, path)); !equalFloats(got, []float64{2}) {
+		t.Errorf("file after recompaction = %v, want [2]", got)
+	}
+
+	// The store keeps appending to the new file
+	if err := store.Append(Record{Time: now, Windows: []Window{{Name: api.WindowFiveHour, Utilization: 3}}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if got := utilizations(readLines(t, path)); !equalFloats(got, []float64{2, 3}) {
+		t.Errorf("file = %v, want [2 3]", got)
+	}
+}
+
+func TestAppendAfterClose(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "nested", FileName), time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	store.Close()
+	if err := store.Append(Record{Time: time.Now()}); err == nil {
+		t.Error("Append() after Close() error = nil")
+	}
+}
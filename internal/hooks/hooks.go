@@ -0,0 +1,221 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types that aren't notifications
+const (
+	EventContext = "context" // Cookies received from the browser extension
+)
+
+// envPrefix starts the names of environment variables passed to hooks
+const envPrefix = "CLAUDECOMPANION_"
+
+// maxOutput is how much of the hook output is logged
+const maxOutput = 8 * 1024
+
+// Event is passed to hooks as environment variables and JSON on stdin
+type Event struct {
+	Type     string            `json:"type"` // low, zero, restored, error, greeting, forecast, context
+	Severity string            `json:"severity,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Window   string            `json:"window,omitempty"`
+	Time     time.Time         `json:"time"`
+	Data     map[string]string `json:"data,omitempty"` // Event specific values, e.g. target_url of context
+}
+
+// Hook is a command executed on events
+type Hook struct {
+	Name    string
+	Events  []string // Event types, empty = all
+	Command string   // Run by the system shell (sh -c, cmd /C)
+	Dir     string   // Working directory, "" = current
+	Timeout time.Duration
+}
+
+// matches returns true if the hook runs on the event type
+func (h Hook) matches(eventType string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if strings.EqualFold(e, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Runner executes hooks in the background with a limit of concurrent commands
+type Runner struct {
+	mu    sync.Mutex
+	hooks []Hook
+	slots chan struct{}
+}
+
+// NewRunner creates a runner without hooks (see SetHooks)
+func NewRunner() *Runner {
+	return &Runner{slots: make(chan struct{}, 1)}
+}
+
+// SetHooks replaces the hooks; running commands are not affected
+func (r *Runner) SetHooks(hooks []Hook, maxConcurrent int) {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = hooks
+	r.slots = make(chan struct{}, maxConcurrent)
+}
+
+// HasHooks returns true if any hook is configured
+func (r *Runner) HasHooks() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.hooks) > 0
+}
+
+// Fire starts all hooks of the event without waiting for them
+// Hooks over the concurrency limit wait for a running one to finish
+func (r *Runner) Fire(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	r.mu.Lock()
+	hooks := r.hooks
+	slots := r.slots
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		if !hook.matches(event.Type) {
+			continue
+		}
+		go func(hook Hook) {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			run(hook, event)
+		}(hook)
+	}
+}
+
+// run executes a hook and logs its output
+func run(hook Hook, event Event) {
+	input, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Hook %s: failed to encode event: %v", hook.Name, err)
+		return
+	}
+
+	ctx := context.Background()
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	cmd := shellCommand(ctx, hook.Command)
+	cmd.Dir = hook.Dir
+	cmd.Env = append(os.Environ(), environment(event)...)
+	cmd.Stdin = bytes.NewReader(input)
+	output := &limitedBuffer{limit: maxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Don't wait for background processes that keep stdout open after the hook is killed
+	cmd.WaitDelay = time.Second
+
+	log.Printf("Hook %s: running on %s event", hook.Name, event.Type)
+	start := time.Now()
+	err = cmd.Run()
+	duration := time.Since(start).Round(time.Millisecond)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output.Bytes()))
+	for scanner.Scan() {
+		log.Printf("Hook %s: %s", hook.Name, scanner.Text())
+	}
+	if output.truncated {
+		log.Printf("Hook %s: output truncated to %d bytes", hook.Name, maxOutput)
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		log.Printf("Hook %s: killed after timeout of %s", hook.Name, hook.Timeout)
+	case err != nil:
+		log.Printf("Hook %s: failed after %s: %v", hook.Name, duration, err)
+	default:
+		log.Printf("Hook %s: finished in %s", hook.Name, duration)
+	}
+}
+
+// shellCommand runs command by the system shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
+
+// environment returns the event as CLAUDECOMPANION_* variables
+func environment(event Event) []string {
+	env := []string{
+		envPrefix + "EVENT=" + event.Type,
+		envPrefix + "SEVERITY=" + event.Severity,
+		envPrefix + "TITLE=" + event.Title,
+		envPrefix + "MESSAGE=" + event.Message,
+		envPrefix + "WINDOW=" + event.Window,
+		envPrefix + "TIME=" + event.Time.Format(time.RFC3339),
+	}
+	keys := make([]string, 0, len(event.Data))
+	for key := range event.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, fmt.Sprintf("%s%s=%s", envPrefix, strings.ToUpper(key), event.Data[key]))
+	}
+	return env
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write implements io.Writer, never fails so the command isn't interrupted
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if left := b.limit - b.buf.Len(); left < len(p) {
+		b.truncated = true
+		if left > 0 {
+			b.buf.Write(p[:left])
+		}
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// Bytes returns the captured output
+func (b *limitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
@@ -25,6 +25,20 @@ const (
 	ColorRed
 )
 
+// String returns the lowercase color name: green, yellow, red or gray
+func (m ColorMode) String() string {
+	switch m {
+	case ColorGreen:
+		return "green"
+	case ColorYellow:
+		return "yellow"
+	case ColorRed:
+		return "red"
+	default:
+		return "gray"
+	}
+}
+
 // Generator creates tray icons
 type Generator struct {
 	colors *config.IconColors
@@ -101,15 +115,21 @@ func (g *Generator) convertToICO(img *image.RGBA) ([]byte, error) {
 
 // getColor returns the color for the given mode
 func (g *Generator) getColor(mode ColorMode) color.Color {
+	c := ModeColor(g.colors, mode)
+	return color.RGBA{c.R, c.G, c.B, 255}
+}
+
+// ModeColor returns the configured color of a mode
+func ModeColor(colors *config.IconColors, mode ColorMode) config.ColorRGB {
 	switch mode {
 	case ColorGreen:
-		return color.RGBA{g.colors.Green.R, g.colors.Green.G, g.colors.Green.B, 255}
+		return colors.Green
 	case ColorYellow:
-		return color.RGBA{g.colors.Yellow.R, g.colors.Yellow.G, g.colors.Yellow.B, 255}
+		return colors.Yellow
 	case ColorRed:
-		return color.RGBA{g.colors.Red.R, g.colors.Red.G, g.colors.Red.B, 255}
+		return colors.Red
 	default:
-		return color.RGBA{g.colors.Gray.R, g.colors.Gray.G, g.colors.Gray.B, 255}
+		return colors.Gray
 	}
 }
 
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// protocolVersions are supported MCP revisions, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// serverName and serverVersion identify the server in the initialize response
+const (
+	serverName    = "claudecompanion"
+	serverVersion = "1.0.0"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeNotFound       = -32002 // Unknown resource
+)
+
+// request is a JSON-RPC request or notification (without id)
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server is an MCP server over stdio (newline delimited JSON-RPC messages)
+type Server struct {
+	source Source
+	mu     sync.Mutex // Serializes writes
+	out    io.Writer
+}
+
+// NewServer creates a server answering from source
+func NewServer(source Source) *Server {
+	return &Server{source: source}
+}
+
+// Serve handles requests from r until it is closed
+// Messages are written to w; stdout must not be used for anything else
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		s.handle(line)
+	}
+	return scanner.Err()
+}
+
+// handle processes a single message
+func (s *Server) handle(data []byte) {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		s.write(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "Parse error"}})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if len(req.ID) > 0 {
+			s.write(response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeInvalidRequest, "Invalid request"}})
+		}
+		return
+	}
+
+	// Notifications (initialized, cancelled) need no handling and get no response
+	if len(req.ID) == 0 {
+		return
+	}
+	result, rpcErr := s.dispatch(req.Method, req.Params)
+	if rpcErr != nil {
+		s.write(response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr})
+		return
+	}
+	s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// dispatch calls the method handler
+func (s *Server) dispatch(method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": toolList}, nil
+	case "tools/call":
+		return s.callTool(params)
+	case "resources/list":
+		return map[string]interface{}{"resources": resourceList}, nil
+	case "resources/read":
+		return s.readResource(params)
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []interface{}{}}, nil
+	case "prompts/list":
+		return map[string]interface{}{"prompts": []interface{}{}}, nil
+	}
+	return nil, &rpcError{codeMethodNotFound, "Method not found: " + method}
+}
+
+// initialize negotiates the protocol version and announces capabilities
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+	}
+
+	version := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
+		},
+		"serverInfo": map[string]string{
+			"name":    serverName,
+			"version": serverVersion,
+		},
+		"instructions": "Quota of the claude.ai subscription from a running ClaudeCompanion. " +
+			"Check get_usage before starting a long task; remaining is in percent.",
+	}, nil
+}
+
+// write sends a message as a single line
+func (s *Server) write(resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("MCP: failed to encode response: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "%s\n", data); err != nil {
+		log.Printf("MCP: failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"claudecompanion/internal/state"
+)
+
+// reply is a decoded response line
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func testSource() (*state.Snapshot, error) {
+	return &state.Snapshot{
+		Windows: []state.WindowState{
+			{Name: "five_hour", Label: "5ч", Utilization: 58, Remaining: 42},
+			{Name: "seven_day", Label: "7д", Utilization: 29, Remaining: 71},
+		},
+		PrimaryWindow: "five_hour",
+		Remaining:     42,
+		HasContext:    true,
+	}, nil
+}
+
+// serve runs the server over the given lines and returns the decoded responses
+func serve(t *testing.T, source Source, lines ...string) []reply {
+	t.Helper()
+	var out bytes.Buffer
+	if err := NewServer(source).Serve(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	var replies []reply
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r reply
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("response %q: %v", line, err)
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+// serveOne runs a single request and returns its response
+func serveOne(t *testing.T, source Source, line string) reply {
+	t.Helper()
+	replies := serve(t, source, line)
+	if len(replies) != 1 {
+		t.Fatalf("got %d responses to %s, want 1", len(replies), line)
+	}
+	return replies[0]
+}
+
+func TestInitializeVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{name: "latest", params: `{"protocolVersion":"2025-06-18"}`, want: "2025-06-18"},
+		{name: "older supported", params: `{"protocolVersion":"2024-11-05"}`, want: "2024-11-05"},
+		{name: "unknown falls back to latest", params: `{"protocolVersion":"2023-01-01"}`, want: protocolVersions[0]},
+		{name: "missing", params: `{}`, want: protocolVersions[0]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := serveOne(t, testSource, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":`+tt.params+`}`)
+			if r.Error != nil {
+				t.Fatalf("initialize error = %+v", r.Error)
+			}
+			var result struct {
+				ProtocolVersion string            `json:"protocolVersion"`
+				ServerInfo      map[string]string `json:"serverInfo"`
+			}
+			if err := json.Unmarshal(r.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.ProtocolVersion != tt.want {
+				t.Errorf("protocolVersion = %q, want %q", result.ProtocolVersion, tt.want)
+			}
+			if result.ServerInfo["name"] != serverName {
+				t.Errorf("serverInfo = %v", result.ServerInfo)
+			}
+		})
+	}
+
+	r := serveOne(t, testSource, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":"2025-06-18"}`)
+	if r.Error == nil || r.Error.Code != codeInvalidParams {
+		t.Errorf("initialize with bad params error = %+v, want %d", r.Error, codeInvalidParams)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	replies := serve(t, testSource,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`,
+		`{"jsonrpc":"2.0","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}`,
+	)
+	// Only the ping has an id and gets a response
+	if len(replies) != 1 || string(replies[0].ID) != "7" || replies[0].Error != nil {
+		t.Fatalf("responses = %+v, want only the ping", replies)
+	}
+
+	// A notification method sent as a request is not a known method
+	r := serveOne(t, testSource, `{"jsonrpc":"2.0","id":"a","method":"notifications/initialized"}`)
+	if string(r.ID) != `"a"` || r.Error == nil || r.Error.Code != codeMethodNotFound {
+		t.Errorf("notification with id = %+v, want error %d", r, codeMethodNotFound)
+	}
+}
+
+func TestInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantCode int // 0 = no response
+	}{
+		{name: "parse error", line: `{"jsonrpc":`, wantCode: codeParseError},
+		{name: "wrong version", line: `{"jsonrpc":"1.0","id":1,"method":"ping"}`, wantCode: codeInvalidRequest},
+		{name: "no method", line: `{"jsonrpc":"2.0","id":1}`, wantCode: codeInvalidRequest},
+		{name: "invalid notification", line: `{"jsonrpc":"1.0","method":"ping"}`},
+		{name: "unknown method", line: `{"jsonrpc":"2.0","id":1,"method":"sampling/createMessage"}`, wantCode: codeMethodNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies := serve(t, testSource, tt.line)
+			if tt.wantCode == 0 {
+				if len(replies) != 0 {
+					t.Errorf("responses = %+v, want none", replies)
+				}
+				return
+			}
+			if len(replies) != 1 || replies[0].Error == nil || replies[0].Error.Code != tt.wantCode {
+				t.Errorf("responses = %+v, want error %d", replies, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	failing := func() (*state.Snapshot, error) { return nil, errors.New("app is not running") }
+
+	tests := []struct {
+		name        string
+		source      Source
+		params      string
+		wantCode    int
+		wantIsError bool
+		wantText    string
+	}{
+		{name: "get_usage", source: testSource, params: `{"name":"get_usage"}`, wantText: `"remaining": 42`},
+		{name: "get_reset_time", source: testSource, params: `{"name":"get_reset_time","arguments":{"window":"seven_day"}}`, wantText: `"window": "seven_day"`},
+		{name: "unknown window", source: testSource, params: `{"name":"get_reset_time","arguments":{"window":"one_hour"}}`, wantIsError: true, wantText: `unknown window "one_hour"`},
+		{name: "no forecast yet", source: testSource, params: `{"name":"get_forecast"}`, wantIsError: true, wantText: "not enough samples"},
+		{name: "source failure", source: failing, params: `{"name":"get_usage"}`, wantIsError: true, wantText: "app is not running"},
+		{name: "unknown tool", source: testSource, params: `{"name":"delete_account"}`, wantCode: codeInvalidParams},
+		{name: "bad params", source: testSource, params: `[]`, wantCode: codeInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := serveOne(t, tt.source, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":`+tt.params+`}`)
+			if tt.wantCode != 0 {
+				if r.Error == nil || r.Error.Code != tt.wantCode {
+					t.Errorf("error = %+v, want %d", r.Error, tt.wantCode)
+				}
+				return
+			}
+			if r.Error != nil {
+				t.Fatalf("error = %+v", r.Error)
+			}
+			var result struct {
+				Content []map[string]string `json:"content"`
+				IsError bool                `json:"isError"`
+			}
+			if err := json.Unmarshal(r.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.wantIsError {
+				t.Errorf("isError = %v, want %v", result.IsError, tt.wantIsError)
+			}
+			if len(result.Content) != 1 || !strings.Contains(result.Content[0]["text"], tt.wantText) {
+				t.Errorf("content = %v, want text containing %q", result.Content, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestReadResource(t *testing.T) {
+	failing := func() (*state.Snapshot, error) { return nil, errors.New("app is not running") }
+
+	tests := []struct {
+		name     string
+		source   Source
+		params   string
+		wantCode int
+	}{
+		{name: "usage", source: testSource, params: `{"uri":"usage://current"}`},
+		{name: "unknown uri", source: testSource, params: `{"uri":"usage://history"}`, wantCode: codeNotFound},
+		{name: "bad params", source: testSource, params: `"usage://current"`, wantCode: codeInvalidParams},
+		{name: "source failure", source: failing, params: `{"uri":"usage://current"}`, wantCode: codeInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := serveOne(t, tt.source, `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":`+tt.params+`}`)
+			if tt.wantCode != 0 {
+				if r.Error == nil || r.Error.Code != tt.wantCode {
+					t.Errorf("error = %+v, want %d", r.Error, tt.wantCode)
+				}
+				return
+			}
+			if r.Error != nil {
+				t.Fatalf("error = %+v", r.Error)
+			}
+			var result struct {
+				Contents []map[string]string `json:"contents"`
+			}
+			if err := json.Unmarshal(r.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Contents) != 1 || result.Contents[0]["uri"] != usageURI || !strings.Contains(result.Contents[0]["text"], `"primary_window": "five_hour"`) {
+				t.Errorf("contents = %v", result.Contents)
+			}
+		})
+	}
+}
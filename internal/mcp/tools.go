@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/state"
+)
+
+// Source returns the current usage, e.g. from the local API of the running app
+type Source func() (*state.Snapshot, error)
+
+// usageURI is the resource with the current usage
+const usageURI = "usage://current"
+
+// windowArgument is the optional input of tools working with a single window
+var windowArgument = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"window": map[string]interface{}{
+			"type":        "string",
+			"description": "Window name, e.g. five_hour or seven_day. Default: the window shown on the tray icon",
+		},
+	},
+}
+
+// toolList is the tools/list result
+var toolList = []map[string]interface{}{
+	{
+		"name":        "get_usage",
+		"title":       "Get quota usage",
+		"description": "Remaining quota (percent) and reset time of every claude.ai usage window, plus error and pause state of the poller",
+		"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+	},
+	{
+		"name":        "get_forecast",
+		"title":       "Get quota forecast",
+		"description": "Burn rate (percent per hour) and projected exhaustion time at the current pace; before_reset tells if the quota runs out before the window resets",
+		"inputSchema": windowArgument,
+	},
+	{
+		"name":        "get_reset_time",
+		"title":       "Get reset time",
+		"description": "When the quota of a window resets and how many minutes are left until then",
+		"inputSchema": windowArgument,
+	},
+}
+
+// resourceList is the resources/list result
+var resourceList = []map[string]interface{}{
+	{
+		"uri":         usageURI,
+		"name":        "current_usage",
+		"title":       "Current quota usage",
+		"description": "Same data as the get_usage tool",
+		"mimeType":    "application/json",
+	},
+}
+
+// windowUsage is a usage window as reported to clients
+type windowUsage struct {
+	Name        string     `json:"name"`
+	Label       string     `json:"label"`
+	Utilization float64    `json:"utilization"`
+	Remaining   int        `json:"remaining"`
+	ResetsAt    *time.Time `json:"resets_at"`
+}
+
+// usageResult is the result of get_usage and the usage://current resource
+type usageResult struct {
+	Windows       []windowUsage     `json:"windows"`
+	PrimaryWindow string            `json:"primary_window,omitempty"`
+	Remaining     int               `json:"remaining"` // Remaining percent of the primary window, -1 if unknown
+	LastSuccess   *time.Time        `json:"last_success"`
+	HasContext    bool              `json:"has_context"` // Cookies were received from the browser extension
+	Paused        bool              `json:"paused"`
+	Error         *state.ErrorState `json:"error"`
+}
+
+// resetResult is the result of get_reset_time
+type resetResult struct {
+	Window         string     `json:"window"`
+	Remaining      int        `json:"remaining"`
+	ResetsAt       *time.Time `json:"resets_at"`
+	MinutesToReset *int       `json:"minutes_to_reset"`
+}
+
+// callTool handles tools/call
+// Failures of the tool itself are reported in the result, so the model can see them
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string `json:"name"`
+		Arguments struct {
+			Window string `json:"window"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+
+	var tool func(*state.Snapshot, string) (interface{}, error)
+	switch p.Name {
+	case "get_usage":
+		tool = func(snap *state.Snapshot, _ string) (interface{}, error) { return usage(snap), nil }
+	case "get_forecast":
+		tool = forecastOf
+	case "get_reset_time":
+		tool = resetTimeOf
+	default:
+		return nil, &rpcError{codeInvalidParams, "Unknown tool: " + p.Name}
+	}
+
+	snap, err := s.source()
+	if err != nil {
+		return toolError(err), nil
+	}
+	result, err := tool(snap, p.Arguments.Window)
+	if err != nil {
+		return toolError(err), nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolError(err), nil
+	}
+	return map[string]interface{}{
+		"content":           []map[string]string{{"type": "text", "text": string(data)}},
+		"structuredContent": result,
+		"isError":           false,
+	}, nil
+}
+
+// toolError is a tools/call result describing a failure
+func toolError(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": err.Error()}},
+		"isError": true,
+	}
+}
+
+// readResource handles resources/read
+func (s *Server) readResource(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+	if p.URI != usageURI {
+		return nil, &rpcError{codeNotFound, "Resource not found: " + p.URI}
+	}
+
+	snap, err := s.source()
+	if err != nil {
+		return nil, &rpcError{codeInternalError, err.Error()}
+	}
+	data, err := json.MarshalIndent(usage(snap), "", "  ")
+	if err != nil {
+		return nil, &rpcError{codeInternalError, err.Error()}
+	}
+	return map[string]interface{}{
+		"contents": []map[string]string{{"uri": usageURI, "mimeType": "application/json", "text": string(data)}},
+	}, nil
+}
+
+// usage converts the snapshot into the get_usage result using the same windows as the tray
+func usage(snap *state.Snapshot) usageResult {
+	response := snap.Usage()
+	result := usageResult{
+		Windows:     make([]windowUsage, 0, len(response.Windows)),
+		Remaining:   -1,
+		LastSuccess: snap.LastSuccess,
+		HasContext:  snap.HasContext,
+		Paused:      snap.Paused,
+		Error:       snap.Error,
+	}
+	for i := range response.Windows {
+		w := &response.Windows[i]
+		result.Windows = append(result.Windows, windowUsage{
+			Name:        w.Name,
+			Label:       w.Label(),
+			Utilization: w.Utilization,
+			Remaining:   w.Remaining(),
+			ResetsAt:    w.ResetsAt,
+		})
+	}
+	if primary := response.PrimaryWindow(); primary != nil {
+		result.PrimaryWindow = primary.Name
+		result.Remaining = primary.Remaining()
+	}
+	return result
+}
+
+// findWindow returns the named window, or the primary window if name is empty
+func findWindow(snap *state.Snapshot, name string) (*api.UsageWindow, error) {
+	response := snap.Usage()
+	var w *api.UsageWindow
+	if name == "" {
+		w = response.PrimaryWindow()
+	} else {
+		w = response.Window(name)
+	}
+	if w != nil {
+		return w, nil
+	}
+	if !snap.HasContext {
+		return nil, fmt.Errorf("no usage data yet: open claude.ai in the browser with the ClaudeCompanion extension")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no usage data yet")
+	}
+	return nil, fmt.Errorf("unknown window %q", name)
+}
+
+// forecastOf implements get_forecast
+func forecastOf(snap *state.Snapshot, name string) (interface{}, error) {
+	w, err := findWindow(snap, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range snap.Forecast {
+		if f.Window == w.Name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("not enough samples of %s for a forecast yet", w.Name)
+}
+
+// resetTimeOf implements get_reset_time
+func resetTimeOf(snap *state.Snapshot, name string) (interface{}, error) {
+	w, err := findWindow(snap, name)
+	if err != nil {
+		return nil, err
+	}
+	result := resetResult{Window: w.Name, Remaining: w.Remaining(), ResetsAt: w.ResetsAt}
+	if w.ResetsAt != nil {
+		minutes := int(time.Until(*w.ResetsAt).Round(time.Minute).Minutes())
+		if minutes < 0 {
+			minutes = 0
+		}
+		result.MinutesToReset = &minutes
+	}
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package metrics
+
+// Application metrics exported on /metrics
+var (
+	// WindowUtilization is the utilization of each usage window (0-100)
+	WindowUtilization = NewGaugeVec("claudecompanion_window_utilization_percent",
+		"Utilization of the usage window in percent.", "window")
+
+	// WindowRemaining is the remaining quota of each usage window (0-100)
+	WindowRemaining = NewGaugeVec("claudecompanion_window_remaining_percent",
+		"Remaining quota of the usage window in percent.", "window")
+
+	// WindowResetSeconds is the time left until each window resets
+	WindowResetSeconds = NewGaugeVec("claudecompanion_window_reset_seconds",
+		"Seconds until the usage window resets.", "window")
+
+	// PollAttempts counts usage requests
+	PollAttempts = NewCounterVec("claudecompanion_poll_attempts_total",
+		"Total number of usage poll attempts.")
+
+	// PollSuccesses counts successful usage requests
+	PollSuccesses = NewCounterVec("claudecompanion_poll_successes_total",
+		"Total number of successful usage polls.")
+
+	// PollFailures counts failed usage requests by error class
+	PollFailures = NewCounterVec("claudecompanion_poll_failures_total",
+		"Total number of failed usage polls by error kind.", "kind")
+
+	// ConsecutiveErrors is the current number of consecutive failed polls
+	ConsecutiveErrors = NewGaugeVec("claudecompanion_consecutive_errors",
+		"Number of consecutive failed usage polls.")
+
+	// RequestDuration is the latency of requests to Claude.ai by transport
+	RequestDuration = NewHistogramVec("claudecompanion_request_duration_seconds",
+		"Duration of requests to Claude.ai in seconds.",
+		[]float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30}, "transport", "request")
+
+	// Notifications counts shown notifications by type
+	Notifications = NewCounterVec("claudecompanion_notifications_total",
+		"Total number of notifications shown by type.", "type")
+
+	// ContextAge is the time since the last /set-context from the extension
+	ContextAge = NewGaugeVec("claudecompanion_context_age_seconds",
+		"Seconds since the browser extension last sent context, -1 if never.")
+)
@@ -0,0 +1,301 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Minimal Prometheus text exposition (format 0.0.4) without external dependencies.
+// Metrics are registered in a package-level registry and served by Handler.
+
+// metric is anything that can write itself in text exposition format
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu  sync.RWMutex
+	registry    []metric
+	beforeWrite []func()
+)
+
+// register adds a metric to the default registry
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// OnScrape registers a callback called before metrics are written
+// Use it to refresh gauges that depend on the current time (e.g. seconds until reset)
+func OnScrape(fn func()) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	beforeWrite = append(beforeWrite, fn)
+}
+
+// WriteText writes all registered metrics in Prometheus text format
+func WriteText(out io.Writer) error {
+	registryMu.RLock()
+	callbacks := append([]func(){}, beforeWrite...)
+	metrics := append([]metric{}, registry...)
+	registryMu.RUnlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+
+	w := bufio.NewWriter(out)
+	for _, m := range metrics {
+		m.write(w)
+	}
+	return w.Flush()
+}
+
+// Handler returns an HTTP handler serving /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
+
+// desc holds common metric metadata
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// writeHeader writes HELP and TYPE lines
+func (d *desc) writeHeader(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// key joins label values into a map key
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelEscaper escapes label values as the text format requires: only backslash, quote and newline
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders {a="x",b="y"} with optional extra label
+func (d *desc) formatLabels(values []string, extraName, extraValue string) string {
+	if len(d.labels) == 0 && extraName == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		parts = append(parts, label+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+labelEscaper.Replace(extraValue)+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatFloat renders a sample value
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// valueVec stores one float per label combination (counters and gauges)
+type valueVec struct {
+	desc
+	kind   string
+	mu     sync.Mutex
+	values map[string]float64
+	keys   map[string][]string
+}
+
+func newValueVec(kind, name, help string, labels []string) *valueVec {
+	v := &valueVec{
+		desc:   desc{name: name, help: help, labels: labels},
+		kind:   kind,
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}
+	// Metrics without labels are exported as 0 from the start
+	if len(labels) == 0 {
+		v.values[""] = 0
+		v.keys[""] = nil
+	}
+	register(v)
+	return v
+}
+
+func (v *valueVec) update(fn func(old float64) float64, labelValues []string) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] = fn(v.values[key])
+	if _, ok := v.keys[key]; !ok {
+		v.keys[key] = append([]string(nil), labelValues...)
+	}
+}
+
+func (v *valueVec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.writeHeader(w, v.kind)
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, v.formatLabels(v.keys[key], "", ""), formatFloat(v.values[key]))
+	}
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	*valueVec
+}
+
+// NewCounterVec creates and registers a counter
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newValueVec("counter", name, help, labels)}
+}
+
+// Inc increments the counter by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter by delta (must be >= 0)
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.update(func(old float64) float64 { return old + delta }, labelValues)
+}
+
+// GaugeVec is a value that can go up and down partitioned by labels
+type GaugeVec struct {
+	*valueVec
+}
+
+// NewGaugeVec creates and registers a gauge
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newValueVec("gauge", name, help, labels)}
+}
+
+// Set sets the gauge value
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.update(func(float64) float64 { return value }, labelValues)
+}
+
+// Replace swaps all label combinations at once, dropping the ones fill doesn't set (e.g. windows that disappeared)
+// The new values are built outside the lock, so a concurrent scrape sees either the old or the new set
+func (g *GaugeVec) Replace(fill func(set func(value float64, labelValues ...string))) {
+	values := make(map[string]float64)
+	keys := make(map[string][]string)
+	fill(func(value float64, labelValues ...string) {
+		key := g.key(labelValues)
+		values[key] = value
+		keys[key] = append([]string(nil), labelValues...)
+	})
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values = values
+	g.keys = keys
+}
+
+// HistogramVec counts observations in cumulative buckets partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // Per bucket, non-cumulative
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec creates and registers a histogram with the given upper bounds
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: sorted,
+		series:  make(map[string]*histogramSeries),
+	}
+	register(h)
+	return h
+}
+
+// Observe adds a single observation
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(s.labelValues, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(s.labelValues, "", ""), s.count)
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// render writes a single metric in text format
+func render(m metric) string {
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	m.write(w)
+	w.Flush()
+	return b.String()
+}
+
+func TestFormatLabelsEscaping(t *testing.T) {
+	d := desc{name: "test", labels: []string{"window"}}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"five_hour", `{window="five_hour"}`},
+		{`say "hi"`, `{window="say \"hi\""}`},
+		{`C:\path`, `{window="C:\\path"}`},
+		{"two\nlines", `{window="two\nlines"}`},
+		{`\"` + "\n", `{window="\\\"\n"}`},
+		{"tab\tand юникод", "{window=\"tab\tand юникод\"}"},
+		{"", `{window=""}`},
+	}
+
+	for _, tt := range tests {
+		if got := d.formatLabels([]string{tt.value}, "", ""); got != tt.want {
+			t.Errorf("formatLabels(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if got := d.formatLabels([]string{`a"b`}, "le", "+Inf"); got != `{window="a\"b",le="+Inf"}` {
+		t.Errorf("formatLabels() with extra label = %s", got)
+	}
+}
+
+func TestGaugeWriteEscapesLabels(t *testing.T) {
+	g := &GaugeVec{&valueVec{
+		desc:   desc{name: "test_gauge", help: "Help\nwith newline", labels: []string{"window"}},
+		kind:   "gauge",
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}}
+	g.Set(42.5, "new \"window\"\n")
+
+	want := "# HELP test_gauge Help with newline\n" +
+		"# TYPE test_gauge gauge\n" +
+		`test_gauge{window="new \"window\"\n"} 42.5` + "\n"
+	if got := render(g); got != want {
+		t.Errorf("write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGaugeReplace(t *testing.T) {
+	g := &GaugeVec{&valueVec{
+		desc:   desc{name: "test_gauge", labels: []string{"window"}},
+		kind:   "gauge",
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}}
+	g.Set(1, "old")
+
+	g.Replace(func(set func(float64, ...string)) {
+		set(10, "five_hour")
+		set(20, "seven_day")
+	})
+	got := render(g)
+	if strings.Contains(got, "old") || !strings.Contains(got, `test_gauge{window="five_hour"} 10`) || !strings.Contains(got, `test_gauge{window="seven_day"} 20`) {
+		t.Errorf("after Replace:\n%s", got)
+	}
+
+	// A concurrent scrape never sees an empty family
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				g.Replace(func(set func(float64, ...string)) {
+					set(10, "five_hour")
+					set(20, "seven_day")
+				})
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if out := render(g); strings.Count(out, "test_gauge{") != 2 {
+			t.Fatalf("scrape %d saw a partial family:\n%s", i, out)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
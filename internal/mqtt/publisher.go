@@ -0,0 +1,289 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"claudecompanion/internal/state"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	// qos is used for all messages, so retained state survives broker hiccups
+	qos = 1
+	// publishTimeout limits how long a publish waits for the broker acknowledgement
+	publishTimeout = 5 * time.Second
+)
+
+// Connection states published to <prefix>/connection
+const (
+	ConnectionOK        = "ok"
+	ConnectionError     = "error"
+	ConnectionNoContext = "no_context"
+)
+
+// TLSSettings configures a TLS connection to the broker
+type TLSSettings struct {
+	CAFile             string // PEM file with the broker CA, "" = system roots
+	CertFile           string // Client certificate for mutual TLS
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// Settings configures the publisher
+type Settings struct {
+	Broker          string // tcp://host:1883, ssl://host:8883, ws://host:9001, ...
+	ClientID        string
+	Username        string
+	Password        string
+	TopicPrefix     string // Base of all state topics
+	Discovery       bool   // Announce Home Assistant discovery configs
+	DiscoveryPrefix string // Home Assistant discovery prefix
+	TLS             TLSSettings
+}
+
+// Publisher mirrors the usage state to retained MQTT topics:
+//
+//	<prefix>/status                  online / offline (last will)
+//	<prefix>/connection              ok / error / no_context
+//	<prefix>/error                   error message, empty when ok
+//	<prefix>/<window>/remaining      remaining percent
+//	<prefix>/<window>/resets_at      RFC 3339 reset time, empty if unknown
+type Publisher struct {
+	settings Settings
+	store    *state.Store
+	client   paho.Client
+
+	mu        sync.Mutex
+	announced map[string]bool // Windows with discovery configs sent in the current connection
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPublisher creates a publisher of the store state, Start connects it
+func NewPublisher(settings Settings, store *state.Store) (*Publisher, error) {
+	if settings.Broker == "" {
+		return nil, errors.New("broker is not set")
+	}
+
+	p := &Publisher{
+		settings:  settings,
+		store:     store,
+		announced: make(map[string]bool),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(settings.Broker).
+		SetClientID(settings.ClientID).
+		SetUsername(settings.Username).
+		SetPassword(settings.Password).
+		SetWill(p.topic("status"), "offline", qos, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(30 * time.Second).
+		SetOrderMatters(false).
+		SetOnConnectHandler(func(paho.Client) { p.onConnect() }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("MQTT connection lost: %v", err)
+		})
+
+	if tlsConfig, err := newTLSConfig(settings); err != nil {
+		return nil, err
+	} else if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	p.client = paho.NewClient(opts)
+	return p, nil
+}
+
+// newTLSConfig builds the TLS config, nil if the broker isn't TLS and no TLS settings are given
+func newTLSConfig(settings Settings) (*tls.Config, error) {
+	t := settings.TLS
+	secure := strings.HasPrefix(settings.Broker, "ssl://") || strings.HasPrefix(settings.Broker, "tls://") ||
+		strings.HasPrefix(settings.Broker, "mqtts://") || strings.HasPrefix(settings.Broker, "wss://")
+	if !secure && t.CAFile == "" && t.CertFile == "" && !t.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Start connects to the broker in the background and publishes every state change
+func (p *Publisher) Start() {
+	log.Printf("MQTT: connecting to %s", p.settings.Broker)
+	p.client.Connect()
+
+	events, unsubscribe := p.store.Subscribe()
+	go func() {
+		defer close(p.done)
+		defer unsubscribe()
+		for {
+			select {
+			case event := <-events:
+				switch event.Type {
+				case state.EventUsage, state.EventError, state.EventContext:
+					p.publishState()
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop publishes "offline" and disconnects
+func (p *Publisher) Stop() {
+	close(p.stop)
+	<-p.done
+	if p.client.IsConnected() {
+		p.publish(p.topic("status"), "offline")
+	}
+	p.client.Disconnect(250)
+	log.Printf("MQTT: disconnected from %s", p.settings.Broker)
+}
+
+// onConnect announces the device and republishes the current state after every (re)connect
+func (p *Publisher) onConnect() {
+	log.Printf("MQTT: connected to %s", p.settings.Broker)
+	p.mu.Lock()
+	p.announced = make(map[string]bool)
+	p.mu.Unlock()
+
+	if p.settings.Discovery {
+		p.announce("connection", "sensor", map[string]interface{}{
+			"name":        "Подключение",
+			"state_topic": p.topic("connection"),
+			"icon":        "mdi:lan-connect",
+		})
+	}
+	p.publish(p.topic("status"), "online")
+	p.publishState()
+}
+
+// publishState publishes the current snapshot
+func (p *Publisher) publishState() {
+	if !p.client.IsConnected() {
+		return
+	}
+	snap := p.store.Snapshot()
+
+	connection := ConnectionOK
+	errorMessage := ""
+	switch {
+	case snap.Error != nil:
+		connection = ConnectionError
+		errorMessage = snap.Error.Message
+	case !snap.HasContext:
+		connection = ConnectionNoContext
+	}
+	p.publish(p.topic("connection"), connection)
+	p.publish(p.topic("error"), errorMessage)
+
+	for _, w := range snap.Windows {
+		if p.settings.Discovery {
+			p.announceWindow(w)
+		}
+		resetsAt := ""
+		if w.ResetsAt != nil {
+			resetsAt = w.ResetsAt.Format(time.RFC3339)
+		}
+		p.publish(p.topic(w.Name, "remaining"), strconv.Itoa(w.Remaining))
+		p.publish(p.topic(w.Name, "resets_at"), resetsAt)
+	}
+}
+
+// announceWindow sends discovery configs of a window once per connection
+func (p *Publisher) announceWindow(w state.WindowState) {
+	p.mu.Lock()
+	done := p.announced[w.Name]
+	p.announced[w.Name] = true
+	p.mu.Unlock()
+	if done {
+		return
+	}
+
+	p.announce(w.Name+"_remaining", "sensor", map[string]interface{}{
+		"name":                "Осталось " + w.Label,
+		"state_topic":         p.topic(w.Name, "remaining"),
+		"unit_of_measurement": "%",
+		"state_class":         "measurement",
+		"icon":                "mdi:gauge",
+	})
+	p.announce(w.Name+"_resets_at", "sensor", map[string]interface{}{
+		"name":           "Сброс " + w.Label,
+		"state_topic":    p.topic(w.Name, "resets_at"),
+		"device_class":   "timestamp",
+		"value_template": "{{ value if value else None }}",
+	})
+}
+
+// announce publishes a Home Assistant discovery config of one entity
+func (p *Publisher) announce(object, component string, config map[string]interface{}) {
+	id := p.settings.ClientID + "_" + object
+	config["unique_id"] = id
+	config["object_id"] = id
+	config["availability_topic"] = p.topic("status")
+	config["device"] = map[string]interface{}{
+		"identifiers":  []string{p.settings.ClientID},
+		"name":         "Claude Companion",
+		"manufacturer": "ClaudeCompanion",
+		"model":        "Claude usage",
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		log.Printf("MQTT: failed to encode discovery config: %v", err)
+		return
+	}
+	p.publish(p.settings.DiscoveryPrefix+"/"+component+"/"+p.settings.ClientID+"/"+object+"/config", string(payload))
+}
+
+// publish sends a retained message and logs failures
+func (p *Publisher) publish(topic, payload string) {
+	token := p.client.Publish(topic, qos, true, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		log.Printf("MQTT: publish to %s timed out", topic)
+		return
+	}
+	if err := token.Error(); err != nil {
+		log.Printf("MQTT: failed to publish to %s: %v", topic, err)
+	}
+}
+
+// topic joins parts under the topic prefix
+func (p *Publisher) topic(parts ...string) string {
+	return p.settings.TopicPrefix + "/" + strings.Join(parts, "/")
+}
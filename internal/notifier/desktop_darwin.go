@@ -0,0 +1,84 @@
+//go:build darwin
+// +build darwin
+
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DesktopSink shows native macOS notification banners using terminal-notifier
+type DesktopSink struct{}
+
+// NewDesktopSink creates the desktop sink
+// embeddedIcon parameter is accepted for API consistency but not used on macOS
+func NewDesktopSink(embeddedIcon []byte) *DesktopSink {
+	return &DesktopSink{}
+}
+
+// Name returns the sink name used in logs
+func (s *DesktopSink) Name() string {
+	return "desktop"
+}
+
+// Send shows a notification; critical urgency ignores Do Not Disturb,
+// sound is a name from /System/Library/Sounds ("" = default, "none" = silent)
+func (s *DesktopSink) Send(event Event) error {
+	args := []string{
+		"-title", "ClaudeCompanion",
+		"-subtitle", event.Title,
+		"-message", event.Message,
+	}
+	switch event.Sound {
+	case "":
+		args = append(args, "-sound", "default")
+	case "none":
+	default:
+		args = append(args, "-sound", event.Sound)
+	}
+	if event.Urgency == "critical" {
+		args = append(args, "-ignoreDnD")
+	}
+
+	// Add icon if available (using contentImage for better visibility)
+	if iconPath := getIconPath(); iconPath != "" {
+		args = append(args, "-contentImage", iconPath)
+	}
+
+	output, err := exec.Command("terminal-notifier", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("terminal-notifier failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// getIconPath returns the path to the app icon for notifications
+func getIconPath() string {
+	// Get executable directory
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	exeDir := filepath.Dir(exePath)
+
+	// Try app-icon.png in the same directory
+	iconPath := filepath.Join(exeDir, "app-icon.png")
+	if _, err := os.Stat(iconPath); err == nil {
+		return iconPath
+	}
+
+	// Try icon96.png from extension folder (when running from source)
+	iconPath = filepath.Join(exeDir, "..", "extension", "icon96.png")
+	if _, err := os.Stat(iconPath); err == nil {
+		absPath, _ := filepath.Abs(iconPath)
+		return absPath
+	}
+
+	log.Printf("[WARNING] No icon found")
+	return ""
+}
@@ -0,0 +1,82 @@
+//go:build linux
+// +build linux
+
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DesktopSink shows native Linux notifications using notify-send
+type DesktopSink struct{}
+
+// NewDesktopSink creates the desktop sink
+// embeddedIcon parameter is accepted for API consistency but not used on Linux
+func NewDesktopSink(embeddedIcon []byte) *DesktopSink {
+	return &DesktopSink{}
+}
+
+// Name returns the sink name used in logs
+func (s *DesktopSink) Name() string {
+	return "desktop"
+}
+
+// Send shows a notification with urgency (low/normal/critical) and sound name ("" = default, "none" = silent)
+func (s *DesktopSink) Send(event Event) error {
+	args := []string{
+		"--app-name=ClaudeCompanion",
+	}
+	if event.Urgency != "" {
+		args = append(args, "--urgency="+event.Urgency)
+	}
+	switch event.Sound {
+	case "":
+	case "none":
+		args = append(args, "--hint=boolean:suppress-sound:true")
+	default:
+		args = append(args, "--hint=string:sound-name:"+event.Sound)
+	}
+
+	// Add icon if available
+	if iconPath := getIconPath(); iconPath != "" {
+		args = append(args, "--icon="+iconPath)
+	}
+	args = append(args, event.Title, event.Message)
+
+	output, err := exec.Command("notify-send", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// getIconPath returns the path to the app icon for notifications
+func getIconPath() string {
+	// Get executable directory
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	exeDir := filepath.Dir(exePath)
+
+	// Try app-icon.png in the same directory
+	iconPath := filepath.Join(exeDir, "app-icon.png")
+	if _, err := os.Stat(iconPath); err == nil {
+		return iconPath
+	}
+
+	// Try icon96.png from extension folder (when running from source)
+	iconPath = filepath.Join(exeDir, "..", "extension", "icon96.png")
+	if _, err := os.Stat(iconPath); err == nil {
+		absPath, _ := filepath.Abs(iconPath)
+		return absPath
+	}
+
+	log.Printf("[WARNING] No icon found")
+	return ""
+}
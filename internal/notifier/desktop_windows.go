@@ -0,0 +1,91 @@
+//go:build windows
+// +build windows
+
+package notifier
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-toast/toast"
+)
+
+// DesktopSink shows Windows toast notifications
+type DesktopSink struct {
+	embeddedIcon []byte
+}
+
+// NewDesktopSink creates the desktop sink
+// embeddedIcon is used when there is no icon.ico next to the executable
+func NewDesktopSink(embeddedIcon []byte) *DesktopSink {
+	return &DesktopSink{embeddedIcon: embeddedIcon}
+}
+
+// Name returns the sink name used in logs
+func (s *DesktopSink) Name() string {
+	return "desktop"
+}
+
+// Send shows a toast notification
+func (s *DesktopSink) Send(event Event) error {
+	notification := toast.Notification{
+		AppID:   "ClaudeCompanion",
+		Title:   event.Title,
+		Message: event.Message,
+		Icon:    s.getIconPath(),
+	}
+	switch event.Sound {
+	case "":
+	case "none":
+		notification.Audio = toast.Silent
+	default:
+		if audio, err := toast.Audio(event.Sound); err == nil {
+			notification.Audio = audio
+		} else {
+			log.Printf("Unknown notification sound %q, using default", event.Sound)
+		}
+	}
+	if event.Urgency == "critical" {
+		notification.Duration = toast.Long
+	}
+	return notification.Push()
+}
+
+// getIconPath returns the path to the application icon
+// Extracts embedded icon to temp file if needed
+func (s *DesktopSink) getIconPath() string {
+	// Try external icon.ico first (for backwards compatibility)
+	exePath, err := os.Executable()
+	if err == nil {
+		exeDir := filepath.Dir(exePath)
+		iconPath := filepath.Join(exeDir, "icon.ico")
+		if _, err := os.Stat(iconPath); err == nil {
+			return iconPath
+		}
+	}
+
+	// If no embedded icon provided, return empty
+	if len(s.embeddedIcon) == 0 {
+		log.Println("No embedded icon available")
+		return ""
+	}
+
+	// Extract embedded icon to temp file
+	tempDir := os.TempDir()
+	tempIconPath := filepath.Join(tempDir, "claudecompanion-icon.ico")
+
+	// Check if temp icon already exists
+	if _, err := os.Stat(tempIconPath); err == nil {
+		return tempIconPath
+	}
+
+	// Write embedded icon to temp file
+	if err := os.WriteFile(tempIconPath, s.embeddedIcon, 0644); err != nil {
+		log.Printf("Failed to write embedded icon to temp file: %v", err)
+		return ""
+	}
+
+	log.Printf("Extracted embedded icon to: %s", tempIconPath)
+	return tempIconPath
+}
@@ -0,0 +1,41 @@
+package notifier
+
+import "sync"
+
+// Notification types reported to listeners
+const (
+	TypeError    = "error"
+	TypeLow      = "low"
+	TypeZero     = "zero"
+	TypeGreeting = "greeting"
+	TypeForecast = "forecast"
+	TypeRestored = "restored"
+	TypeTest     = "test" // Delivered to every sink regardless of its filter
+)
+
+// Listener is called for every notification, before sinks have delivered it
+// Listeners run synchronously under notifier state lock and must not call the Notifier
+type Listener func(notificationType, title, message string)
+
+var (
+	listenersMu sync.RWMutex
+	listeners   []Listener
+)
+
+// AddListener registers a listener for shown notifications (metrics, event stream, ...)
+func AddListener(listener Listener) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	listeners = append(listeners, listener)
+}
+
+// emit reports a shown notification to all listeners
+func emit(notificationType, title, message string) {
+	listenersMu.RLock()
+	current := append([]Listener(nil), listeners...)
+	listenersMu.RUnlock()
+
+	for _, listener := range current {
+		listener(notificationType, title, message)
+	}
+}
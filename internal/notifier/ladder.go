@@ -0,0 +1,194 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/config"
+)
+
+// LadderStateFile is the file with notified levels, stored next to config.yaml
+const LadderStateFile = "notification_state.json"
+
+// ladderEntry is the most severe level notified in the current period of a window
+type ladderEntry struct {
+	Threshold int        `json:"threshold"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+// ladderFile is the layout of the state file
+type ladderFile struct {
+	Account string                 `json:"account,omitempty"`
+	Windows map[string]ladderEntry `json:"windows"`
+}
+
+// Ladder tracks which low quota level of every window has been notified
+// The state is saved to disk so a restart doesn't replay alerts of the current period
+type Ladder struct {
+	mu      sync.Mutex
+	path    string // "" keeps the state in memory only
+	account string // Organization the levels belong to
+	windows map[string]ladderEntry
+}
+
+// NewLadder creates a ladder persisted at path (empty path = in memory)
+// Entries of periods that already reset are dropped
+func NewLadder(path string) *Ladder {
+	l := &Ladder{
+		path:    path,
+		windows: make(map[string]ladderEntry),
+	}
+	if path == "" {
+		return l
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read notification state: %v", err)
+		}
+		return l
+	}
+	var file ladderFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("Failed to parse notification state, starting over: %v", err)
+		return l
+	}
+	if file.Windows == nil {
+		return l // State of an older version without the account
+	}
+	l.account = file.Account
+	l.windows = file.Windows
+
+	now := time.Now()
+	for window, entry := range l.windows {
+		if entry.ResetsAt != nil && !entry.ResetsAt.After(now) {
+			delete(l.windows, window)
+		}
+	}
+	return l
+}
+
+// Step records the remaining value of a window and returns the level to notify, nil if none
+// levels must be sorted by threshold from highest to lowest (see WindowNotifications.Ladder)
+//   - only the most severe crossed level is notified, intermediate ones are skipped
+//   - a level is armed again only after the value rises above its threshold + hysteresis
+//   - a new period (ResetsAt changed) starts from scratch
+func (l *Ladder) Step(window string, value int, resetsAt *time.Time, levels []config.NotificationLevel, hysteresis int) *config.NotificationLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, notified := l.windows[window]
+	changed := false
+	if notified && api.ResetMoved(entry.ResetsAt, resetsAt) {
+		delete(l.windows, window)
+		notified = false
+		changed = true
+	}
+
+	// Most severe level the value is at or below
+	var target *config.NotificationLevel
+	for i := range levels {
+		if value <= levels[i].Threshold {
+			target = &levels[i]
+		}
+	}
+
+	// Value went back up: arm the levels above it again
+	if notified && value > entry.Threshold+hysteresis {
+		if target == nil {
+			delete(l.windows, window)
+			notified = false
+		} else {
+			entry.Threshold = target.Threshold
+			l.windows[window] = entry
+		}
+		changed = true
+	}
+
+	var result *config.NotificationLevel
+	if target != nil && (!notified || target.Threshold < entry.Threshold) {
+		l.windows[window] = ladderEntry{Threshold: target.Threshold, ResetsAt: resetsAt}
+		result = target
+		changed = true
+	} else if notified && resetsAt != nil && entry.ResetsAt == nil {
+		entry.ResetsAt = resetsAt
+		l.windows[window] = entry
+		changed = true
+	}
+
+	if changed {
+		l.save()
+	}
+	return result
+}
+
+// Level returns the threshold of the most severe level notified for a window in the current period
+func (l *Ladder) Level(window string) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.windows[window]
+	return entry.Threshold, ok
+}
+
+// SetAccount binds the state to an organization; levels notified for another one are forgotten,
+// so switching accounts doesn't suppress alerts of the new account
+func (l *Ladder) SetAccount(account string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if account == l.account {
+		return
+	}
+	if len(l.windows) > 0 {
+		log.Printf("Organization changed, notification levels reset")
+	}
+	l.account = account
+	l.windows = make(map[string]ladderEntry)
+	l.save()
+}
+
+// Reset forgets the notified level of a window
+func (l *Ladder) Reset(window string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.windows[window]; ok {
+		delete(l.windows, window)
+		l.save()
+	}
+}
+
+// save writes the state to disk (caller holds l.mu)
+func (l *Ladder) save() {
+	if l.path == "" {
+		return
+	}
+	if err := l.write(); err != nil {
+		log.Printf("Failed to save notification state: %v", err)
+	}
+}
+
+// write replaces the state file atomically
+func (l *Ladder) write() error {
+	data, err := json.MarshalIndent(ladderFile{Account: l.account, Windows: l.windows}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"claudecompanion/internal/config"
+)
+
+// ladderStep is one poll fed to Ladder.Step; want is the notified threshold, -1 = none
+type ladderStep struct {
+	value    int
+	resetsAt *time.Time
+	want     int
+}
+
+func testLevels() []config.NotificationLevel {
+	return config.WindowNotifications{Levels: []config.NotificationLevel{
+		{Threshold: 10},
+		{Threshold: 0},
+		{Threshold: 50},
+	}}.Ladder()
+}
+
+func TestLadderStep(t *testing.T) {
+	period := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	drifted := period.Add(30 * time.Second)
+	next := period.Add(5 * time.Hour)
+
+	tests := []struct {
+		name       string
+		hysteresis int
+		steps      []ladderStep
+	}{
+		{
+			name:       "each level once",
+			hysteresis: 5,
+			steps: []ladderStep{
+				{60, &period, -1}, {50, &period, 50}, {45, &period, -1}, {10, &period, 10},
+				{8, &period, -1}, {0, &period, 0}, {0, &period, -1},
+			},
+		},
+		{
+			name:       "sharp drop skips to the most severe level",
+			hysteresis: 5,
+			steps:      []ladderStep{{60, &period, -1}, {8, &period, 10}, {20, &period, -1}, {0, &period, 0}},
+		},
+		{
+			name:       "bouncing within hysteresis",
+			hysteresis: 5,
+			steps:      []ladderStep{{50, &period, 50}, {54, &period, -1}, {50, &period, -1}, {55, &period, -1}, {49, &period, -1}},
+		},
+		{
+			name:       "rising above hysteresis re-arms",
+			hysteresis: 5,
+			steps:      []ladderStep{{50, &period, 50}, {56, &period, -1}, {50, &period, 50}},
+		},
+		{
+			name:       "rising to a higher level re-arms the lower one",
+			hysteresis: 5,
+			steps:      []ladderStep{{10, &period, 10}, {12, &period, -1}, {10, &period, -1}, {16, &period, -1}, {10, &period, 10}},
+		},
+		{
+			name:       "zero hysteresis",
+			hysteresis: 0,
+			steps:      []ladderStep{{50, &period, 50}, {51, &period, -1}, {50, &period, 50}},
+		},
+		{
+			name:       "new period starts from scratch",
+			hysteresis: 5,
+			steps:      []ladderStep{{40, &period, 50}, {40, &next, 50}, {5, &next, 10}},
+		},
+		{
+			name:       "reset time drift is the same period",
+			hysteresis: 5,
+			steps:      []ladderStep{{40, &period, 50}, {40, &drifted, -1}, {40, &period, -1}},
+		},
+		{
+			name:       "reset time learned later",
+			hysteresis: 5,
+			steps:      []ladderStep{{40, nil, 50}, {40, &period, -1}, {40, &next, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLadder("")
+			for i, step := range tt.steps {
+				got := -1
+				if level := l.Step("five_hour", step.value, step.resetsAt, testLevels(), tt.hysteresis); level != nil {
+					got = level.Threshold
+				}
+				if got != step.want {
+					t.Fatalf("step %d (value %d): notified %d, want %d", i, step.value, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLadderWindowsAreIndependent(t *testing.T) {
+	l := NewLadder("")
+	if level := l.Step("five_hour", 5, nil, testLevels(), 5); level == nil || level.Threshold != 10 {
+		t.Fatalf("five_hour: %+v", level)
+	}
+	if level := l.Step("seven_day", 40, nil, testLevels(), 5); level == nil || level.Threshold != 50 {
+		t.Fatalf("seven_day: %+v", level)
+	}
+
+	l.Reset("five_hour")
+	if _, ok := l.Level("five_hour"); ok {
+		t.Error("five_hour level kept after Reset")
+	}
+	if threshold, ok := l.Level("seven_day"); !ok || threshold != 50 {
+		t.Errorf("seven_day level = %d, %v, want 50", threshold, ok)
+	}
+}
+
+func TestLadderPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LadderStateFile)
+	resetsAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	expired := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	l := NewLadder(path)
+	l.SetAccount("org-1")
+	l.Step("five_hour", 8, &resetsAt, testLevels(), 5)
+	l.Step("seven_day", 40, &expired, testLevels(), 5)
+
+	// Restart: the current period is restored, the finished one is dropped
+	restored := NewLadder(path)
+	if threshold, ok := restored.Level("five_hour"); !ok || threshold != 10 {
+		t.Errorf("five_hour level = %d, %v, want 10", threshold, ok)
+	}
+	if _, ok := restored.Level("seven_day"); ok {
+		t.Error("level of a finished period was restored")
+	}
+	if level := restored.Step("five_hour", 8, &resetsAt, testLevels(), 5); level != nil {
+		t.Errorf("restart replayed level %d", level.Threshold)
+	}
+
+	// Same organization keeps the state, another one starts over
+	restored.SetAccount("org-1")
+	if _, ok := restored.Level("five_hour"); !ok {
+		t.Error("SetAccount with the same organization dropped the state")
+	}
+	restored.SetAccount("org-2")
+	if _, ok := NewLadder(path).Level("five_hour"); ok {
+		t.Error("levels of the previous organization were saved")
+	}
+}
+
+func TestLadderIgnoresUnreadableState(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"corrupt", `{"windows":`},
+		{"older format", `{"five_hour":{"threshold":10}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), LadderStateFile)
+			if err := os.WriteFile(path, []byte(tt.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			l := NewLadder(path)
+			if level := l.Step("five_hour", 8, nil, testLevels(), 5); level == nil || level.Threshold != 10 {
+				t.Errorf("Step() = %+v, want level 10", level)
+			}
+			if _, ok := NewLadder(path).Level("five_hour"); !ok {
+				t.Error("state was not rewritten in the current format")
+			}
+		})
+	}
+}
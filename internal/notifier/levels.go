@@ -0,0 +1,30 @@
+package notifier
+
+import "claudecompanion/internal/config"
+
+// windowTitle appends the window label to a notification title: "Низкая квота: 7д"
+func windowTitle(title, label string) string {
+	if label == "" {
+		return title
+	}
+	return title + ": " + label
+}
+
+// levelType returns the listener notification type of a ladder level
+func levelType(level config.NotificationLevel) string {
+	if level.Threshold <= 0 {
+		return TypeZero
+	}
+	return TypeLow
+}
+
+// levelSeverity maps the urgency of a ladder level to a sink severity
+func levelSeverity(level config.NotificationLevel) Severity {
+	switch level.Urgency {
+	case "critical":
+		return SeverityCritical
+	case "low":
+		return SeverityInfo
+	}
+	return SeverityWarning
+}
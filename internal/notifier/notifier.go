@@ -1,157 +1,169 @@
-//go:build windows
-// +build windows
-
 package notifier
 
 import (
 	"log"
-	"os"
-	"path/filepath"
 	"sync"
+	"time"
 
-	"github.com/go-toast/toast"
+	"claudecompanion/internal/config"
 )
 
 // NotificationState tracks which notifications have been shown
 type NotificationState struct {
 	mu                    sync.Mutex
 	lastErrorNotification bool
-	lastLowValueNotif     bool
-	lastZeroNotif         bool
+	lastForecastNotif     bool
 }
 
-// Notifier handles system notifications
+// Notifier decides which notifications to show and fans them out to sinks
 type Notifier struct {
-	state        *NotificationState
-	embeddedIcon []byte
+	state *NotificationState
+
+	sinksMu sync.Mutex
+	workers []*worker
 }
 
-// NewNotifier creates a new notifier
-func NewNotifier(embeddedIcon []byte) *Notifier {
+// NewNotifier creates a notifier without sinks (see SetSinks)
+func NewNotifier() *Notifier {
 	return &Notifier{
-		state:        &NotificationState{},
-		embeddedIcon: embeddedIcon,
+		state: &NotificationState{},
 	}
 }
 
-// getIconPath returns the path to the application icon
-// Extracts embedded icon to temp file if needed
-func (n *Notifier) getIconPath() string {
-	// Try external icon.ico first (for backwards compatibility)
-	exePath, err := os.Executable()
-	if err == nil {
-		exeDir := filepath.Dir(exePath)
-		iconPath := filepath.Join(exeDir, "icon.ico")
-		if _, err := os.Stat(iconPath); err == nil {
-			return iconPath
-		}
+// SetSinks replaces the sinks notifications are delivered to
+// Events already queued for the previous sinks are still delivered
+func (n *Notifier) SetSinks(routes []Route) {
+	workers := make([]*worker, 0, len(routes))
+	for _, route := range routes {
+		workers = append(workers, startWorker(route))
 	}
 
-	// If no embedded icon provided, return empty
-	if len(n.embeddedIcon) == 0 {
-		log.Println("No embedded icon available")
-		return ""
+	n.sinksMu.Lock()
+	previous := n.workers
+	n.workers = workers
+	n.sinksMu.Unlock()
+
+	for _, w := range previous {
+		w.stop()
 	}
+}
 
-	// Extract embedded icon to temp file
-	tempDir := os.TempDir()
-	tempIconPath := filepath.Join(tempDir, "claudecompanion-icon.ico")
+// Close stops all sinks after delivering queued events
+func (n *Notifier) Close() {
+	n.SetSinks(nil)
+}
 
-	// Check if temp icon already exists
-	if _, err := os.Stat(tempIconPath); err == nil {
-		return tempIconPath
-	}
+// dispatch reports a notification to listeners and queues it for every matching sink
+func (n *Notifier) dispatch(event Event) {
+	event.Time = time.Now()
+	log.Printf("Notification (%s, %s): %s: %s", event.Type, event.Severity, event.Title, event.Message)
 
-	// Write embedded icon to temp file
-	if err := os.WriteFile(tempIconPath, n.embeddedIcon, 0644); err != nil {
-		log.Printf("Failed to write embedded icon to temp file: %v", err)
-		return ""
+	n.sinksMu.Lock()
+	for _, w := range n.workers {
+		w.offer(event)
 	}
+	n.sinksMu.Unlock()
 
-	log.Printf("Extracted embedded icon to: %s", tempIconPath)
-	return tempIconPath
+	emit(event.Type, event.Title, event.Message)
 }
 
-// NotifyError shows an error notification (for authorization issues)
-func (n *Notifier) NotifyError(errorCount int, threshold int) {
+// NotifyError shows an error notification describing the failure and how to fix it
+func (n *Notifier) NotifyError(errorCount int, threshold int, title, message string) {
 	n.state.mu.Lock()
 	defer n.state.mu.Unlock()
 
 	if errorCount >= threshold && !n.state.lastErrorNotification {
-		title := "Проблема с авторизацией"
-		message := "Сайт не принимает запросы. Возможно, сессия устарела. Пожалуйста, зайдите на сайт и обновите авторизацию. 🔐"
-
-		log.Printf("Attempting to show error notification")
-		notification := toast.Notification{
-			AppID:   "ClaudeCompanion",
-			Title:   title,
-			Message: message,
-			Icon:    n.getIconPath(),
-		}
-		if err := notification.Push(); err != nil {
-			log.Printf("Failed to show notification: %v", err)
-		} else {
-			log.Println("Error notification shown successfully")
-		}
-
 		n.state.lastErrorNotification = true
+		n.dispatch(Event{
+			Type:     TypeError,
+			Severity: SeverityCritical,
+			Title:    title,
+			Message:  message,
+		})
 	}
 }
 
-// NotifyLowValue shows a notification when value is low
-func (n *Notifier) NotifyLowValue(value int, phrase string) {
+// NotifyLevel shows a low quota notification of a ladder level
+// label is the window label shown in the title, empty for the five-hour window
+// resetTime is added to the message of the zero level
+func (n *Notifier) NotifyLevel(label string, level config.NotificationLevel, phrase string, resetTime string) {
 	n.state.mu.Lock()
 	defer n.state.mu.Unlock()
 
-	if value > 0 && !n.state.lastLowValueNotif {
-		title := "Низкая квота"
-		message := phrase
-
-		log.Printf("Attempting to show low value notification: %s", phrase)
-		notification := toast.Notification{
-			AppID:   "ClaudeCompanion",
-			Title:   title,
-			Message: message,
-			Icon:    n.getIconPath(),
-		}
-		if err := notification.Push(); err != nil {
-			log.Printf("Failed to show notification: %v", err)
-		} else {
-			log.Printf("Low value notification shown successfully: %s", phrase)
-		}
-
-		n.state.lastLowValueNotif = true
-		n.state.lastZeroNotif = false // Reset zero notification state
+	message := phrase
+	if level.Threshold <= 0 {
+		message = phrase + "\nВозвращайся в " + resetTime
 	}
+
+	n.dispatch(Event{
+		Type:     levelType(level),
+		Severity: levelSeverity(level),
+		Title:    windowTitle(level.Title, label),
+		Message:  message,
+		Window:   label,
+		Urgency:  level.Urgency,
+		Sound:    level.Sound,
+	})
 }
 
-// NotifyZero shows a notification when value reaches zero
-func (n *Notifier) NotifyZero(phrase string, resetTime string) {
+// NotifyForecast warns that the quota runs out before reset at the current pace
+// Shown once until ResetForecastNotification is called
+func (n *Notifier) NotifyForecast(message string) {
 	n.state.mu.Lock()
 	defer n.state.mu.Unlock()
 
-	if !n.state.lastZeroNotif {
-		title := "Квота исчерпана"
-		message := phrase + "\nВозвращайся в " + resetTime
-
-		log.Printf("Attempting to show zero notification: %s", message)
-		notification := toast.Notification{
-			AppID:   "ClaudeCompanion",
-			Title:   title,
-			Message: message,
-			Icon:    n.getIconPath(),
-		}
-		if err := notification.Push(); err != nil {
-			log.Printf("Failed to show notification: %v", err)
-		} else {
-			log.Printf("Zero notification shown successfully: %s", message)
-		}
-
-		n.state.lastZeroNotif = true
-		n.state.lastLowValueNotif = false // Reset low value state
+	if !n.state.lastForecastNotif {
+		n.state.lastForecastNotif = true
+		n.dispatch(Event{
+			Type:     TypeForecast,
+			Severity: SeverityWarning,
+			Title:    "Лимит кончится до сброса",
+			Message:  message,
+		})
 	}
 }
 
+// NotifyRestored shows a notification when a window has reset and quota is available again
+// label is the window label shown in the title, empty for the five-hour window
+func (n *Notifier) NotifyRestored(label string, message string) {
+	n.state.mu.Lock()
+	defer n.state.mu.Unlock()
+
+	n.dispatch(Event{
+		Type:     TypeRestored,
+		Severity: SeverityInfo,
+		Title:    windowTitle("Квота восстановлена", label),
+		Message:  message,
+		Window:   label,
+	})
+}
+
+// NotifyGreeting shows a notification when greeting is sent
+func (n *Notifier) NotifyGreeting() {
+	n.state.mu.Lock()
+	defer n.state.mu.Unlock()
+
+	n.dispatch(Event{
+		Type:     TypeGreeting,
+		Severity: SeverityInfo,
+		Title:    "Утренний привет Клоду ☀️",
+		Message:  "Сообщение отправлено успешно!",
+	})
+}
+
+// NotifyTest sends a test notification to every sink, ignoring sink filters
+func (n *Notifier) NotifyTest() {
+	n.state.mu.Lock()
+	defer n.state.mu.Unlock()
+
+	n.dispatch(Event{
+		Type:     TypeTest,
+		Severity: SeverityInfo,
+		Title:    "Тестовое уведомление",
+		Message:  "Если вы это видите, уведомления работают ✅",
+	})
+}
+
 // ResetErrorNotification resets the error notification state
 func (n *Notifier) ResetErrorNotification() {
 	n.state.mu.Lock()
@@ -162,14 +174,13 @@ func (n *Notifier) ResetErrorNotification() {
 	}
 }
 
-// ResetLowValueNotification resets low value notification state
-func (n *Notifier) ResetLowValueNotification() {
+// ResetForecastNotification resets the forecast notification state
+func (n *Notifier) ResetForecastNotification() {
 	n.state.mu.Lock()
 	defer n.state.mu.Unlock()
-	if n.state.lastLowValueNotif || n.state.lastZeroNotif {
-		n.state.lastLowValueNotif = false
-		n.state.lastZeroNotif = false
-		log.Println("Low value notification state reset")
+	if n.state.lastForecastNotif {
+		n.state.lastForecastNotif = false
+		log.Println("Forecast notification state reset")
 	}
 }
 
@@ -178,26 +189,6 @@ func (n *Notifier) ResetAll() {
 	n.state.mu.Lock()
 	defer n.state.mu.Unlock()
 	n.state.lastErrorNotification = false
-	n.state.lastLowValueNotif = false
-	n.state.lastZeroNotif = false
+	n.state.lastForecastNotif = false
 	log.Println("All notification states reset")
 }
-
-// NotifyGreeting shows a notification when greeting is sent
-func (n *Notifier) NotifyGreeting() {
-	title := "Утренний привет Клоду ☀️"
-	//message := "Сообщение отправлено успешно! ☀️"
-
-	log.Printf("Attempting to show greeting notification")
-	notification := toast.Notification{
-		AppID: "ClaudeCompanion",
-		Title: title,
-		//Message: message,
-		Icon: n.getIconPath(),
-	}
-	if err := notification.Push(); err != nil {
-		log.Printf("Failed to show greeting notification: %v", err)
-	} else {
-		log.Println("Greeting notification shown successfully")
-	}
-}
@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Severity orders notifications by importance for sink filters
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// String returns the config name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+// ParseSeverity parses "info", "warning" or "critical" ("" = info)
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "info":
+		return SeverityInfo, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q", name)
+}
+
+// MarshalText encodes the severity by name in JSON payloads
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Event is a notification delivered to sinks
+type Event struct {
+	Type     string    `json:"type"` // TypeError, TypeLow, TypeZero, ...
+	Severity Severity  `json:"severity"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Window   string    `json:"window,omitempty"` // Window label, "" for the five-hour window and events not tied to a window
+	Urgency  string    `json:"-"`                // Desktop urgency hint: "low", "normal" or "critical" ("" = default)
+	Sound    string    `json:"-"`                // Desktop sound name, "" = default, "none" = silent
+	Time     time.Time `json:"time"`
+}
+
+// Sink delivers notifications to one destination (desktop, chat, webhook, ...)
+// Send is called from a dedicated goroutine per sink, so it may block on I/O
+type Sink interface {
+	Name() string
+	Send(event Event) error
+}
+
+// Filter selects the events delivered to a sink
+type Filter struct {
+	Types       []string // Event types to deliver, empty = all
+	MinSeverity Severity
+}
+
+// NewFilter creates a filter from config values
+func NewFilter(types []string, minSeverity string) (Filter, error) {
+	severity, err := ParseSeverity(minSeverity)
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{Types: types, MinSeverity: severity}, nil
+}
+
+// Match returns true if the event passes the filter
+func (f Filter) Match(event Event) bool {
+	if event.Severity < f.MinSeverity {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if strings.EqualFold(t, event.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Route is a sink together with its filter
+type Route struct {
+	Sink   Sink
+	Filter Filter
+}
+
+// sinkQueueSize is how many events may wait for a slow sink before new ones are dropped
+const sinkQueueSize = 32
+
+// worker delivers events to one sink in order
+type worker struct {
+	route Route
+	queue chan Event
+}
+
+// startWorker starts delivering queued events to a sink
+func startWorker(route Route) *worker {
+	w := &worker{
+		route: route,
+		queue: make(chan Event, sinkQueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// run sends queued events until the queue is closed
+func (w *worker) run() {
+	name := w.route.Sink.Name()
+	for event := range w.queue {
+		if err := w.route.Sink.Send(event); err != nil {
+			log.Printf("Sink %s failed to deliver %s notification: %v", name, event.Type, err)
+		} else {
+			log.Printf("Sink %s delivered %s notification: %s", name, event.Type, event.Title)
+		}
+	}
+}
+
+// offer queues an event if it passes the filter, without blocking
+func (w *worker) offer(event Event) {
+	if event.Type != TypeTest && !w.route.Filter.Match(event) {
+		return
+	}
+	select {
+	case w.queue <- event:
+	default:
+		log.Printf("Sink %s is too slow, %s notification dropped", w.route.Sink.Name(), event.Type)
+	}
+}
+
+// stop lets the worker deliver what's queued and exit
+func (w *worker) stop() {
+	close(w.queue)
+}
@@ -0,0 +1,225 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultTelegramBaseURL is the public Bot API
+	DefaultTelegramBaseURL = "https://api.telegram.org"
+	// defaultTelegramTemplate is used when no template is configured
+	defaultTelegramTemplate = "{{.Title}}\n{{.Message}}"
+	// telegramTimeout limits a single Bot API request
+	telegramTimeout = 15 * time.Second
+	// maxRetryAfter caps how long a "Too Many Requests" response may delay delivery
+	maxRetryAfter = time.Minute
+)
+
+// TelegramSettings configures the Telegram sink
+type TelegramSettings struct {
+	BaseURL     string            // Bot API base URL, "" = DefaultTelegramBaseURL
+	Token       string            // Bot token from @BotFather
+	ChatIDs     []string          // Chats to send notifications to
+	Proxy       string            // Proxy URL, empty = HTTP(S)_PROXY environment
+	ParseMode   string            // "", "HTML" or "MarkdownV2"
+	Template    string            // Message template, "" = title and message
+	Templates   map[string]string // Templates by event type, override Template
+	MinInterval time.Duration     // Minimum delay between two messages
+}
+
+// TelegramSink sends notifications to Telegram chats via the Bot API
+type TelegramSink struct {
+	settings  TelegramSettings
+	client    *http.Client
+	template  *template.Template
+	templates map[string]*template.Template
+
+	mu       sync.Mutex
+	lastSent time.Time
+}
+
+// NewTelegramSink creates a Telegram sink, templates are parsed upfront
+func NewTelegramSink(settings TelegramSettings) (*TelegramSink, error) {
+	if settings.Token == "" {
+		return nil, errors.New("bot token is not set")
+	}
+	if len(settings.ChatIDs) == 0 {
+		return nil, errors.New("no chat IDs")
+	}
+	if settings.BaseURL == "" {
+		settings.BaseURL = DefaultTelegramBaseURL
+	}
+	settings.BaseURL = strings.TrimRight(settings.BaseURL, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", settings.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	s := &TelegramSink{
+		settings:  settings,
+		client:    &http.Client{Transport: transport, Timeout: telegramTimeout},
+		templates: make(map[string]*template.Template),
+	}
+
+	text := settings.Template
+	if text == "" {
+		text = defaultTelegramTemplate
+	}
+	var err error
+	if s.template, err = s.parse("default", text); err != nil {
+		return nil, err
+	}
+	for eventType, text := range settings.Templates {
+		if s.templates[eventType], err = s.parse(eventType, text); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parse parses a message template; {{escape .Title}} escapes text for the parse mode
+func (s *TelegramSink) parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{"escape": s.escape}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+// escape escapes text for the configured parse mode
+func (s *TelegramSink) escape(text string) string {
+	switch s.settings.ParseMode {
+	case "HTML":
+		return html.EscapeString(text)
+	case "MarkdownV2":
+		var b strings.Builder
+		for _, r := range text {
+			if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return text
+}
+
+// Name returns the sink name used in logs
+func (s *TelegramSink) Name() string {
+	return "telegram"
+}
+
+// Send renders the event and sends it to every chat
+func (s *TelegramSink) Send(event Event) error {
+	t := s.template
+	if typed, ok := s.templates[event.Type]; ok {
+		t = typed
+	}
+	var text bytes.Buffer
+	if err := t.Execute(&text, event); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	var errs []error
+	for _, chatID := range s.settings.ChatIDs {
+		if err := s.sendMessage(chatID, text.String()); err != nil {
+			errs = append(errs, fmt.Errorf("chat %s: %w", chatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// telegramResponse is the common Bot API response envelope
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// sendMessage calls sendMessage, waiting for the rate limit and retrying once after "Too Many Requests"
+func (s *TelegramSink) sendMessage(chatID, text string) error {
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if s.settings.ParseMode != "" {
+		payload["parse_mode"] = s.settings.ParseMode
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		s.wait()
+		response, err := s.post("sendMessage", body)
+		if err != nil {
+			return err
+		}
+		if response.OK {
+			return nil
+		}
+		retryAfter := time.Duration(response.Parameters.RetryAfter) * time.Second
+		if response.ErrorCode == http.StatusTooManyRequests && attempt == 0 && retryAfter <= maxRetryAfter {
+			s.delay(retryAfter)
+			continue
+		}
+		return fmt.Errorf("bot API error %d: %s", response.ErrorCode, response.Description)
+	}
+}
+
+// post calls a Bot API method; the token is removed from errors so it never reaches the log
+func (s *TelegramSink) post(method string, body []byte) (*telegramResponse, error) {
+	endpoint := s.settings.BaseURL + "/bot" + s.settings.Token + "/" + method
+	resp, err := s.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New(strings.ReplaceAll(err.Error(), s.settings.Token, "***"))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var response telegramResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return &response, nil
+}
+
+// wait sleeps until MinInterval has passed since the previous message
+func (s *TelegramSink) wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d := time.Until(s.lastSent.Add(s.settings.MinInterval)); d > 0 {
+		time.Sleep(d)
+	}
+	s.lastSent = time.Now()
+}
+
+// delay postpones the next message by d
+func (s *TelegramSink) delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSent = time.Now().Add(d - s.settings.MinInterval)
+}
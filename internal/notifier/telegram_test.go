@@ -0,0 +1,258 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// botRequest is a sendMessage call received by the fake Bot API
+type botRequest struct {
+	path    string
+	payload map[string]string
+	at      time.Time
+}
+
+// fakeBotAPI is a local stand-in for the Telegram Bot API
+// respond returns the response body of the n-th request (0-based)
+type fakeBotAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []botRequest
+}
+
+func newFakeBotAPI(t *testing.T, respond func(n int) string) *fakeBotAPI {
+	api := &fakeBotAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var payload map[string]string
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Errorf("invalid request body %q: %v", data, err)
+		}
+
+		api.mu.Lock()
+		n := len(api.requests)
+		api.requests = append(api.requests, botRequest{path: r.URL.Path, payload: payload, at: time.Now()})
+		api.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, respond(n))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *fakeBotAPI) received() []botRequest {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]botRequest(nil), api.requests...)
+}
+
+func okResponse(int) string {
+	return `{"ok":true,"result":{}}`
+}
+
+func TestTelegramSinkTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  TelegramSettings
+		event     Event
+		wantText  string
+		wantParse string
+	}{
+		{
+			name:     "default template",
+			event:    Event{Type: TypeLow, Title: "Низкая квота", Message: "Пора отдохнуть"},
+			wantText: "Низкая квота\nПора отдохнуть",
+		},
+		{
+			name:     "custom template with event fields",
+			settings: TelegramSettings{Template: "[{{.Severity}}] {{.Title}} {{.Window}}"},
+			event:    Event{Type: TypeZero, Severity: SeverityCritical, Title: "Квота исчерпана", Window: "7д"},
+			wantText: "[critical] Квота исчерпана 7д",
+		},
+		{
+			name: "template by event type",
+			settings: TelegramSettings{
+				Template:  "{{.Title}}",
+				Templates: map[string]string{TypeRestored: "✅ {{.Message}}"},
+			},
+			event:    Event{Type: TypeRestored, Title: "Квота восстановлена", Message: "С возвращением!"},
+			wantText: "✅ С возвращением!",
+		},
+		{
+			name:      "html escaping",
+			settings:  TelegramSettings{ParseMode: "HTML", Template: "<b>{{escape .Title}}</b>"},
+			event:     Event{Type: TypeError, Title: "5 < 10 & more"},
+			wantText:  "<b>5 &lt; 10 &amp; more</b>",
+			wantParse: "HTML",
+		},
+		{
+			name:      "markdown escaping",
+			settings:  TelegramSettings{ParseMode: "MarkdownV2", Template: "*{{escape .Title}}*"},
+			event:     Event{Type: TypeError, Title: "HTTP 429 (retry in 1.5s)!"},
+			wantText:  `*HTTP 429 \(retry in 1\.5s\)\!*`,
+			wantParse: "MarkdownV2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeBotAPI(t, okResponse)
+			settings := tt.settings
+			settings.BaseURL = api.URL + "/"
+			settings.Token = "123:secret"
+			settings.ChatIDs = []string{"1", "-100200"}
+
+			sink, err := NewTelegramSink(settings)
+			if err != nil {
+				t.Fatalf("NewTelegramSink() error = %v", err)
+			}
+			if err := sink.Send(tt.event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			requests := api.received()
+			if len(requests) != 2 {
+				t.Fatalf("got %d requests, want one per chat", len(requests))
+			}
+			for i, req := range requests {
+				if req.path != "/bot123:secret/sendMessage" {
+					t.Errorf("path = %q", req.path)
+				}
+				if req.payload["chat_id"] != settings.ChatIDs[i] {
+					t.Errorf("chat_id = %q, want %q", req.payload["chat_id"], settings.ChatIDs[i])
+				}
+				if req.payload["text"] != tt.wantText {
+					t.Errorf("text = %q, want %q", req.payload["text"], tt.wantText)
+				}
+				if req.payload["parse_mode"] != tt.wantParse {
+					t.Errorf("parse_mode = %q, want %q", req.payload["parse_mode"], tt.wantParse)
+				}
+			}
+		})
+	}
+}
+
+func TestTelegramSinkInvalidTemplate(t *testing.T) {
+	_, err := NewTelegramSink(TelegramSettings{Token: "t", ChatIDs: []string{"1"}, Templates: map[string]string{TypeLow: "{{.Title"}})
+	if err == nil || !strings.Contains(err.Error(), "low template") {
+		t.Fatalf("NewTelegramSink() error = %v, want invalid low template", err)
+	}
+}
+
+func TestTelegramSinkRetriesAfterTooManyRequests(t *testing.T) {
+	const tooManyRequests = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`
+
+	tests := []struct {
+		name         string
+		respond      func(n int) string
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name: "retried once",
+			respond: func(n int) string {
+				if n == 0 {
+					return tooManyRequests
+				}
+				return okResponse(n)
+			},
+			wantRequests: 2,
+		},
+		{
+			name:         "gives up after the second 429",
+			respond:      func(int) string { return tooManyRequests },
+			wantRequests: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "other errors are not retried",
+			respond:      func(int) string { return `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}` },
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeBotAPI(t, tt.respond)
+			sink, err := NewTelegramSink(TelegramSettings{BaseURL: api.URL, Token: "123:secret", ChatIDs: []string{"1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sink.Send(Event{Type: TypeLow, Title: "t", Message: "m"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "secret") {
+				t.Errorf("error leaks the token: %v", err)
+			}
+
+			requests := api.received()
+			if len(requests) != tt.wantRequests {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.wantRequests)
+			}
+			if len(requests) == 2 {
+				if gap := requests[1].at.Sub(requests[0].at); gap < 900*time.Millisecond {
+					t.Errorf("retried after %s, want retry_after (1s)", gap)
+				}
+			}
+		})
+	}
+}
+
+func TestTelegramSinkMinInterval(t *testing.T) {
+	const minInterval = 300 * time.Millisecond
+
+	api := newFakeBotAPI(t, okResponse)
+	sink, err := NewTelegramSink(TelegramSettings{
+		BaseURL:     api.URL,
+		Token:       "123:secret",
+		ChatIDs:     []string{"1", "2"},
+		MinInterval: minInterval,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Send(Event{Type: TypeLow, Title: "t"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	requests := api.received()
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(requests))
+	}
+	for i := 1; i < len(requests); i++ {
+		// Allow for timer granularity
+		if gap := requests[i].at.Sub(requests[i-1].at); gap < minInterval-20*time.Millisecond {
+			t.Errorf("request %d came %s after the previous one, want at least %s", i, gap, minInterval)
+		}
+	}
+}
+
+func TestTelegramSinkHidesTokenInErrors(t *testing.T) {
+	api := newFakeBotAPI(t, okResponse)
+	api.Close()
+
+	sink, err := NewTelegramSink(TelegramSettings{BaseURL: api.URL, Token: "123:secret", ChatIDs: []string{"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Send(Event{Type: TypeLow})
+	if err == nil {
+		t.Fatal("Send() error = nil, want connection error")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks the token: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// DeadLetterFile is the log of webhook deliveries that kept failing, stored next to config.yaml
+	DeadLetterFile = "webhook_dead_letter.jsonl"
+	// SignatureHeader carries the HMAC-SHA256 of the body when a secret is set
+	SignatureHeader = "X-ClaudeCompanion-Signature"
+	// webhookTimeout limits a single delivery attempt
+	webhookTimeout = 15 * time.Second
+)
+
+// WebhookSettings configures a webhook sink
+type WebhookSettings struct {
+	Name     string            // Name used in logs and the dead-letter log
+	URL      string            // Endpoint URL
+	Method   string            // "" = POST
+	Format   string            // "json" (default) or "form"
+	Template string            // Body template for json, "" = the event as a JSON object
+	Form     map[string]string // Field templates for form, empty = all event fields
+	Headers  map[string]string // Extra request headers
+	Secret   string            // HMAC-SHA256 key, "" = unsigned
+	Retries  int               // Attempts after the first one
+	Backoff  time.Duration     // Delay before the first retry, doubled for every next one
+}
+
+// WebhookSink posts notifications to an HTTP endpoint
+type WebhookSink struct {
+	settings   WebhookSettings
+	client     *http.Client
+	template   *template.Template
+	form       map[string]*template.Template
+	deadLetter *DeadLetter
+}
+
+// webhookFuncs are available in webhook templates
+var webhookFuncs = template.FuncMap{
+	// json encodes a value as JSON: {"text": {{json .Message}}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewWebhookSink creates a webhook sink, templates are parsed upfront
+// deadLetter may be nil to only log failed deliveries
+func NewWebhookSink(settings WebhookSettings, deadLetter *DeadLetter) (*WebhookSink, error) {
+	if settings.URL == "" {
+		return nil, errors.New("url is not set")
+	}
+	if _, err := url.ParseRequestURI(settings.URL); err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if settings.Method == "" {
+		settings.Method = http.MethodPost
+	}
+	if settings.Name == "" {
+		settings.Name = settings.URL
+	}
+
+	s := &WebhookSink{
+		settings:   settings,
+		client:     &http.Client{Timeout: webhookTimeout},
+		form:       make(map[string]*template.Template),
+		deadLetter: deadLetter,
+	}
+
+	var err error
+	switch settings.Format {
+	case "", "json":
+		if settings.Template != "" {
+			if s.template, err = template.New("body").Funcs(webhookFuncs).Parse(settings.Template); err != nil {
+				return nil, fmt.Errorf("invalid template: %w", err)
+			}
+		}
+	case "form":
+		for field, text := range settings.Form {
+			if s.form[field], err = template.New(field).Funcs(webhookFuncs).Parse(text); err != nil {
+				return nil, fmt.Errorf("invalid template of form field %s: %w", field, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", settings.Format)
+	}
+	return s, nil
+}
+
+// Name returns the sink name used in logs
+func (s *WebhookSink) Name() string {
+	return "webhook " + s.settings.Name
+}
+
+// Send delivers the event, retrying with backoff; failed deliveries go to the dead-letter log
+func (s *WebhookSink) Send(event Event) error {
+	body, contentType, err := s.render(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.settings.Backoff
+	attempts := 0
+	for {
+		attempts++
+		var retry bool
+		retry, err = s.deliver(body, contentType)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempts > s.settings.Retries {
+			break
+		}
+		log.Printf("Webhook %s attempt %d failed, retrying in %s: %v", s.settings.Name, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	if s.deadLetter != nil {
+		s.deadLetter.Add(DeadLetterRecord{
+			Time:     time.Now(),
+			Webhook:  s.settings.Name,
+			URL:      s.settings.URL,
+			Event:    event,
+			Body:     string(body),
+			Error:    err.Error(),
+			Attempts: attempts,
+		})
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", attempts, err)
+}
+
+// render builds the request body
+func (s *WebhookSink) render(event Event) ([]byte, string, error) {
+	if s.settings.Format == "form" {
+		values := url.Values{}
+		if len(s.form) == 0 {
+			values.Set("type", event.Type)
+			values.Set("severity", event.Severity.String())
+			values.Set("title", event.Title)
+			values.Set("message", event.Message)
+			values.Set("window", event.Window)
+			values.Set("time", event.Time.Format(time.RFC3339))
+		}
+		for field, t := range s.form {
+			var value strings.Builder
+			if err := t.Execute(&value, event); err != nil {
+				return nil, "", fmt.Errorf("failed to render form field %s: %w", field, err)
+			}
+			values.Set(field, value.String())
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	if s.template == nil {
+		data, err := json.Marshal(event)
+		return data, "application/json", err
+	}
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, event); err != nil {
+		return nil, "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return body.Bytes(), "application/json", nil
+}
+
+// deliver makes a single attempt; retry is false for errors that won't go away (4xx except 408 and 429)
+func (s *WebhookSink) deliver(body []byte, contentType string) (retry bool, err error) {
+	req, err := http.NewRequest(s.settings.Method, s.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "ClaudeCompanion")
+	for name, value := range s.settings.Headers {
+		req.Header.Set(name, value)
+	}
+	if s.settings.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.settings.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(response)))
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, err
+}
+
+// DeadLetterRecord is a webhook delivery that kept failing
+type DeadLetterRecord struct {
+	Time     time.Time `json:"time"`
+	Webhook  string    `json:"webhook"`
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Body     string    `json:"body"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+}
+
+// DeadLetter appends failed deliveries to a JSONL file shared by all webhooks
+type DeadLetter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewDeadLetter creates a dead-letter log at path
+func NewDeadLetter(path string) *DeadLetter {
+	return &DeadLetter{path: path}
+}
+
+// Add appends a record to the log
+func (d *DeadLetter) Add(record DeadLetterRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Failed to encode dead-letter record: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		log.Printf("Failed to write dead-letter log: %v", err)
+		return
+	}
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Printf("Failed to open dead-letter log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write dead-letter log: %v", err)
+	}
+}
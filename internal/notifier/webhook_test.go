@@ -0,0 +1,333 @@
+package notifier
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookRequest is a delivery received by the fake webhook endpoint
+type hookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// fakeWebhook answers the n-th delivery (0-based) with the status returned by respond
+type fakeWebhook struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []hookRequest
+}
+
+func newFakeWebhook(t *testing.T, respond func(n int) int) *fakeWebhook {
+	hook := &fakeWebhook{}
+	hook.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		hook.mu.Lock()
+		n := len(hook.requests)
+		hook.requests = append(hook.requests, hookRequest{header: r.Header.Clone(), body: body})
+		hook.mu.Unlock()
+
+		w.WriteHeader(respond(n))
+		io.WriteString(w, "response body")
+	}))
+	t.Cleanup(hook.Close)
+	return hook
+}
+
+func (hook *fakeWebhook) received() []hookRequest {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	return append([]hookRequest(nil), hook.requests...)
+}
+
+func status(code int) func(int) int {
+	return func(int) int { return code }
+}
+
+var testEvent = Event{
+	Type:     TypeLow,
+	Severity: SeverityWarning,
+	Title:    "Низкая квота",
+	Message:  "Осталось 10%",
+	Window:   "7д",
+	Time:     time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC),
+}
+
+func TestWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"signed", "s3cret"},
+		{"unsigned", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newFakeWebhook(t, status(http.StatusNoContent))
+			sink, err := NewWebhookSink(WebhookSettings{
+				URL:     hook.URL,
+				Secret:  tt.secret,
+				Headers: map[string]string{"Authorization": "Bearer x"},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Send(testEvent); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := hook.received()[0]
+			if got := req.header.Get("Authorization"); got != "Bearer x" {
+				t.Errorf("Authorization = %q", got)
+			}
+			signature := req.header.Get(SignatureHeader)
+			if tt.secret == "" {
+				if signature != "" {
+					t.Errorf("unsigned webhook sent %s: %s", SignatureHeader, signature)
+				}
+				return
+			}
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write(req.body)
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+				t.Errorf("%s = %q, want %q", SignatureHeader, signature, want)
+			}
+		})
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	tests := []struct {
+		name            string
+		settings        WebhookSettings
+		wantContentType string
+		check           func(t *testing.T, body []byte)
+	}{
+		{
+			name:            "event as json",
+			wantContentType: "application/json",
+			check: func(t *testing.T, body []byte) {
+				var got map[string]string
+				if err := json.Unmarshal(body, &got); err != nil || got["title"] != testEvent.Title || got["severity"] != "warning" ||
+					got["window"] != "7д" || got["time"] != "2026-01-02T10:00:00Z" {
+					t.Errorf("body = %s (%v)", body, err)
+				}
+			},
+		},
+		{
+			name:            "json template",
+			settings:        WebhookSettings{Template: `{"text": {{json .Message}}, "type": "{{.Type}}"}`},
+			wantContentType: "application/json",
+			check: func(t *testing.T, body []byte) {
+				if string(body) != `{"text": "Осталось 10%", "type": "low"}` {
+					t.Errorf("body = %s", body)
+				}
+			},
+		},
+		{
+			name:            "form fields",
+			settings:        WebhookSettings{Format: "form", Form: map[string]string{"text": "{{.Title}}: {{.Message}}"}},
+			wantContentType: "application/x-www-form-urlencoded",
+			check: func(t *testing.T, body []byte) {
+				values, err := url.ParseQuery(string(body))
+				if err != nil || len(values) != 1 || values.Get("text") != "Низкая квота: Осталось 10%" {
+					t.Errorf("body = %s (%v)", body, err)
+				}
+			},
+		},
+		{
+			name:            "form with all fields",
+			settings:        WebhookSettings{Format: "form"},
+			wantContentType: "application/x-www-form-urlencoded",
+			check: func(t *testing.T, body []byte) {
+				values, _ := url.ParseQuery(string(body))
+				if values.Get("severity") != "warning" || values.Get("window") != "7д" || values.Get("time") != "2026-01-02T10:00:00Z" {
+					t.Errorf("body = %s", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newFakeWebhook(t, status(http.StatusOK))
+			settings := tt.settings
+			settings.URL = hook.URL
+			sink, err := NewWebhookSink(settings, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Send(testEvent); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			req := hook.received()[0]
+			if got := req.header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			tt.check(t, req.body)
+		})
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		respond      func(n int) int
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:    "5xx then success",
+			retries: 3,
+			respond: func(n int) int {
+				if n < 2 {
+					return http.StatusBadGateway
+				}
+				return http.StatusOK
+			},
+			wantRequests: 3,
+		},
+		{name: "5xx until retries run out", retries: 2, respond: status(http.StatusServiceUnavailable), wantRequests: 3, wantErr: true},
+		{name: "429 is retried", retries: 1, respond: status(http.StatusTooManyRequests), wantRequests: 2, wantErr: true},
+		{name: "408 is retried", retries: 1, respond: status(http.StatusRequestTimeout), wantRequests: 2, wantErr: true},
+		{name: "4xx is not retried", retries: 3, respond: status(http.StatusBadRequest), wantRequests: 1, wantErr: true},
+		{name: "401 is not retried", retries: 3, respond: status(http.StatusUnauthorized), wantRequests: 1, wantErr: true},
+		{name: "retries off", retries: 0, respond: status(http.StatusInternalServerError), wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newFakeWebhook(t, tt.respond)
+			sink, err := NewWebhookSink(WebhookSettings{URL: hook.URL, Retries: tt.retries, Backoff: time.Millisecond}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sink.Send(testEvent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if got := len(hook.received()); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoffDoubles(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	const backoff = 50 * time.Millisecond
+	sink, err := NewWebhookSink(WebhookSettings{URL: srv.URL, Retries: 2, Backoff: backoff}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Send(testEvent)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("got %d requests, want 3", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < backoff {
+		t.Errorf("first retry after %s, want at least %s", gap, backoff)
+	}
+	if gap := times[2].Sub(times[1]); gap < 2*backoff {
+		t.Errorf("second retry after %s, want at least %s", gap, 2*backoff)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", DeadLetterFile)
+	deadLetter := NewDeadLetter(path)
+
+	failing := newFakeWebhook(t, status(http.StatusInternalServerError))
+	sink, err := NewWebhookSink(WebhookSettings{Name: "ha", URL: failing.URL, Retries: 1, Backoff: time.Millisecond}, deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testEvent); err == nil {
+		t.Fatal("Send() error = nil")
+	}
+
+	ok := newFakeWebhook(t, status(http.StatusOK))
+	sink, err = NewWebhookSink(WebhookSettings{Name: "ok", URL: ok.URL}, deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testEvent); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	// Event severity is written as a string, so the event is checked as raw JSON
+	type record struct {
+		DeadLetterRecord
+		Event json.RawMessage `json:"event"`
+	}
+	var records []record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid dead-letter line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("got %d dead-letter records, want only the failed delivery", len(records))
+	}
+	rec := records[0]
+	if rec.Webhook != "ha" || rec.URL != failing.URL || rec.Attempts != 2 || !strings.Contains(string(rec.Event), `"title":"Низкая квота"`) ||
+		!strings.Contains(rec.Error, "HTTP 500") || !strings.Contains(rec.Body, "Осталось 10%") {
+		t.Errorf("dead-letter record = %+v", rec)
+	}
+}
+
+func TestNewWebhookSinkValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings WebhookSettings
+	}{
+		{"no url", WebhookSettings{}},
+		{"relative url", WebhookSettings{URL: "hooks/x"}},
+		{"unknown format", WebhookSettings{URL: "https://example.com", Format: "xml"}},
+		{"invalid template", WebhookSettings{URL: "https://example.com", Template: "{{.Title"}},
+		{"invalid form template", WebhookSettings{URL: "https://example.com", Format: "form", Form: map[string]string{"text": "{{"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewWebhookSink(tt.settings, nil); err == nil {
+			t.Errorf("%s: NewWebhookSink() error = nil", tt.name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Control actions performed by POST /control/<action>
+const (
+	ActionRefresh    = "refresh"     // Poll now, responds after the poll finished
+	ActionPause      = "pause"       // Stop automatic polling
+	ActionResume     = "resume"      // Resume automatic polling
+	ActionNotifyTest = "notify-test" // Send a test notification to all sinks
+)
+
+// ErrUnknownAction is returned by a ControlHandler for actions it doesn't support
+var ErrUnknownAction = errors.New("unknown action")
+
+// ControlHandler performs a control action requested by a CLI command
+type ControlHandler func(action string) error
+
+// SetControlHandler sets the handler of /control/<action> requests
+func (s *Server) SetControlHandler(handler ControlHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onControl = handler
+}
+
+// handleControl handles POST /control/<action>
+// The pairing token is always required, even if pairing of the extension is disabled
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	token := s.pairingToken
+	handler := s.onControl
+	s.mu.RUnlock()
+
+	// Web pages must not control the app even if they guess the token
+	if origin := r.Header.Get("Origin"); origin != "" && !isExtensionOrigin(origin) {
+		log.Printf("Rejected control request: origin %q", origin)
+		writeJSONError(w, http.StatusForbidden, "forbidden_origin", "Control requests are not accepted from web pages")
+		return
+	}
+	if !tokenMatches(token, r.Header.Get(PairingHeader)) {
+		log.Printf("Rejected control request: missing or invalid pairing token")
+		writeJSONError(w, http.StatusUnauthorized, "pairing_required", "Missing or invalid pairing token")
+		return
+	}
+	if handler == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "not_ready", "Control is not available")
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, "/control/")
+	log.Printf("Control request: %s", action)
+	if err := handler(action); err != nil {
+		if errors.Is(err, ErrUnknownAction) {
+			writeJSONError(w, http.StatusNotFound, "unknown_action", "Unknown action: "+action)
+			return
+		}
+		writeJSONError(w, http.StatusConflict, "failed", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ok",
+		"action": action,
+	})
+}
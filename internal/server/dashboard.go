@@ -0,0 +1,70 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed web/index.html
+var dashboardHTML []byte
+
+// maxHistoryHours limits the /history range
+const maxHistoryHours = 7 * 24
+
+// handleDashboard serves the embedded web dashboard at /
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// Everything is inline, nothing is loaded from other origins
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write(dashboardHTML)
+}
+
+// handleHistory handles the /history endpoint (recent samples, errors and notifications)
+// Query parameter hours limits the range (default 24, max one week)
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	store := s.usageState
+	s.mu.RUnlock()
+
+	if store == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "not_ready", "Usage state is not available")
+		return
+	}
+
+	hours := 24
+	if value := r.URL.Query().Get("hours"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_hours", "hours must be a positive integer")
+			return
+		}
+		hours = parsed
+	}
+	if hours > maxHistoryHours {
+		hours = maxHistoryHours
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(store.History(since))
+}
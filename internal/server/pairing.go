@@ -0,0 +1,137 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PairingHeader carries the pairing token from the browser extension
+const PairingHeader = "X-Pairing-Token"
+
+// PairingTokenFile is the token file name, stored next to config.yaml
+const PairingTokenFile = "pairing.token"
+
+// extensionOriginSchemes are Origin schemes of browser extension pages
+var extensionOriginSchemes = []string{"moz-extension://", "chrome-extension://", "safari-web-extension://"}
+
+// LoadOrCreatePairingToken reads the pairing token from path or generates a new one
+func LoadOrCreatePairingToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := normalizeToken(string(data)); token != "" {
+			return formatToken(token), nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read pairing token: %w", err)
+	}
+
+	return RegeneratePairingToken(path)
+}
+
+// ReadPairingToken reads an existing pairing token without creating one
+func ReadPairingToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pairing token: %w", err)
+	}
+	token := normalizeToken(string(data))
+	if token == "" {
+		return "", fmt.Errorf("pairing token file %s is empty", path)
+	}
+	return formatToken(token), nil
+}
+
+// RegeneratePairingToken generates a new pairing token and saves it to path
+// The extension has to be paired again after this
+func RegeneratePairingToken(path string) (string, error) {
+	// 10 random bytes = 80 bits = 16 base32 characters
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate pairing token: %w", err)
+	}
+	token := formatToken(base32.StdEncoding.EncodeToString(buf))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create token directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save pairing token: %w", err)
+	}
+	return token, nil
+}
+
+// normalizeToken strips separators and whitespace so "abcd-efgh" matches "ABCDEFGH"
+func normalizeToken(token string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(token) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatToken groups a token in blocks of four characters: XXXX-XXXX-XXXX-XXXX
+func formatToken(token string) string {
+	token = normalizeToken(token)
+	var groups []string
+	for len(token) > 4 {
+		groups = append(groups, token[:4])
+		token = token[4:]
+	}
+	groups = append(groups, token)
+	return strings.Join(groups, "-")
+}
+
+// tokenMatches compares tokens in constant time ignoring case and separators
+func tokenMatches(expected, got string) bool {
+	e := normalizeToken(expected)
+	g := normalizeToken(got)
+	if e == "" || g == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(e), []byte(g)) == 1
+}
+
+// isExtensionOrigin returns true for Origin headers of browser extension pages
+func isExtensionOrigin(origin string) bool {
+	for _, scheme := range extensionOriginSchemes {
+		if strings.HasPrefix(origin, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTargetURL checks that targetURL is https and its host is in allowedHosts
+// (exact match or subdomain)
+func validateTargetURL(targetURL string, allowedHosts []string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("invalid targetUrl: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("targetUrl must use https, got %q", u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("targetUrl must not contain credentials")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("targetUrl host %q is not in allowed hosts %v", host, allowedHosts)
+}
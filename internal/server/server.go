@@ -6,6 +6,10 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"time"
+
+	"claudecompanion/internal/metrics"
+	"claudecompanion/internal/state"
 )
 
 // ContextData represents the data received from browser extension
@@ -18,20 +22,36 @@ type ContextData struct {
 
 // Server handles HTTP requests from browser extension
 type Server struct {
-	port         int
-	mu           sync.RWMutex
-	contextData  *ContextData
-	onContextSet func(cookies, targetURL, organizationID string, headers map[string]string)
-	httpServer   *http.Server
+	port            int
+	mu              sync.RWMutex
+	contextData     *ContextData
+	onContextSet    func(cookies, targetURL, organizationID string, headers map[string]string)
+	httpServer      *http.Server
+	pairingToken    string   // Secret shared with the extension
+	pairingRequired bool     // Reject /set-context without valid token and extension origin
+	allowedHosts    []string // Hosts accepted as targetUrl
+	usageState      *state.Store
+	onControl       ControlHandler
 }
 
 // NewServer creates a new HTTP server
 func NewServer(port int) *Server {
 	return &Server{
-		port: port,
+		port:            port,
+		pairingRequired: true,
+		allowedHosts:    []string{"claude.ai"},
 	}
 }
 
+// SetPairing configures the pairing token, whether it's required and the allowed targetUrl hosts
+func (s *Server) SetPairing(token string, required bool, allowedHosts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pairingToken = token
+	s.pairingRequired = required
+	s.allowedHosts = allowedHosts
+}
+
 // SetContextCallback sets the callback to be called when context is updated
 func (s *Server) SetContextCallback(callback func(cookies, targetURL, organizationID string, headers map[string]string)) {
 	s.mu.Lock()
@@ -39,15 +59,18 @@ func (s *Server) SetContextCallback(callback func(cookies, targetURL, organizati
 	s.onContextSet = callback
 }
 
+// SetStateStore sets the shared usage state served by /usage, /events and /history
+func (s *Server) SetStateStore(store *state.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usageState = store
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/set-context", s.handleSetContext)
-	mux.HandleFunc("/health", s.handleHealth)
-
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf("127.0.0.1:%d", s.port),
-		Handler: s.corsMiddleware(mux),
+		Handler: s.handler(),
 	}
 
 	log.Printf("Starting HTTP server on port %d", s.port)
@@ -61,6 +84,20 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// handler returns the routes of the server wrapped in the CORS middleware
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/set-context", s.handleSetContext)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/usage", s.handleUsage)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/history", s.handleHistory)
+	mux.HandleFunc("/control/", s.handleControl)
+	mux.HandleFunc("/", s.handleDashboard)
+	mux.Handle("/metrics", metrics.Handler())
+	return s.corsMiddleware(mux)
+}
+
 // Stop gracefully stops the HTTP server
 func (s *Server) Stop() error {
 	if s.httpServer != nil {
@@ -86,6 +123,26 @@ func (s *Server) handleSetContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.mu.RLock()
+	token := s.pairingToken
+	required := s.pairingRequired
+	allowedHosts := s.allowedHosts
+	s.mu.RUnlock()
+
+	if required {
+		origin := r.Header.Get("Origin")
+		if !isExtensionOrigin(origin) {
+			log.Printf("Rejected context: origin %q is not a browser extension", origin)
+			writeJSONError(w, http.StatusForbidden, "forbidden_origin", "Requests are accepted only from the browser extension")
+			return
+		}
+		if !tokenMatches(token, r.Header.Get(PairingHeader)) {
+			log.Printf("Rejected context: missing or invalid pairing token (origin %s)", origin)
+			writeJSONError(w, http.StatusUnauthorized, "pairing_required", "Missing or invalid pairing token")
+			return
+		}
+	}
+
 	var data ContextData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 		log.Printf("Failed to decode context data: %v", err)
@@ -98,6 +155,12 @@ func (s *Server) handleSetContext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateTargetURL(data.TargetURL, allowedHosts); err != nil {
+		log.Printf("Rejected context: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "invalid_target_url", err.Error())
+		return
+	}
+
 	s.mu.Lock()
 	s.contextData = &data
 	callback := s.onContextSet
@@ -119,21 +182,136 @@ func (s *Server) handleSetContext(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleHealth handles the /health endpoint
+// Reports whether the pairing token sent in the header is valid so the extension can verify pairing
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	paired := !s.pairingRequired || tokenMatches(s.pairingToken, r.Header.Get(PairingHeader))
+	s.mu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":  "ok",
 		"version": "1.0.0",
+		"paired":  paired,
+	})
+}
+
+// handleUsage handles the /usage endpoint (read-only snapshot of the latest poll)
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	store := s.usageState
+	s.mu.RUnlock()
+
+	if store == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "not_ready", "Usage state is not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(store.Snapshot())
+}
+
+// sseKeepAlive is the interval of comment lines that keep idle /events connections open
+const sseKeepAlive = 30 * time.Second
+
+// handleEvents handles the /events endpoint (Server-Sent Events stream of state changes)
+// The current snapshot is sent first, then usage, error, context and notification events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	store := s.usageState
+	s.mu.RUnlock()
+
+	if store == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "not_ready", "Usage state is not available")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, state.Event{Type: "snapshot", Time: time.Now(), Data: store.Snapshot()}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a single SSE message with the event type and JSON payload
+func writeEvent(w http.ResponseWriter, event state.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// writeJSONError writes a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"error":   code,
+		"message": message,
 	})
 }
 
 // corsMiddleware adds CORS headers
+// Only browser extension origins are allowed, so web pages can't talk to the local server
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow requests from browser extensions
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if origin := r.Header.Get("Origin"); isExtensionOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+PairingHeader)
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
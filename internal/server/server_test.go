@@ -0,0 +1,223 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testToken  = "ABCD-EFGH-2345-6789"
+	testOrigin = "moz-extension://0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0"
+	testTarget = "https://claude.ai/api/organizations/org/usage"
+)
+
+// newTestServer starts the server routes on an httptest listener
+func newTestServer(t *testing.T, pairingRequired bool) (*Server, *httptest.Server) {
+	s := NewServer(0)
+	s.SetPairing(testToken, pairingRequired, []string{"claude.ai"})
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+// post sends a POST request with optional Origin and pairing token headers
+func post(t *testing.T, url, origin, token, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if token != "" {
+		req.Header.Set(PairingHeader, token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestSetContextPairing(t *testing.T) {
+	contextBody := func(target string) string {
+		return `{"cookies":"sessionKey=abc","targetUrl":"` + target + `","organizationId":"org"}`
+	}
+
+	tests := []struct {
+		name       string
+		disabled   bool
+		origin     string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "paired extension", origin: testOrigin, token: testToken, body: contextBody(testTarget), wantStatus: http.StatusOK},
+		{name: "token typed without dashes", origin: "chrome-extension://abc", token: "abcdefgh23456789", body: contextBody(testTarget), wantStatus: http.StatusOK},
+		{name: "subdomain target", origin: testOrigin, token: testToken, body: contextBody("https://api.claude.ai/usage"), wantStatus: http.StatusOK},
+		{name: "no origin", token: testToken, body: contextBody(testTarget), wantStatus: http.StatusForbidden},
+		{name: "web page origin", origin: "https://evil.example", token: testToken, body: contextBody(testTarget), wantStatus: http.StatusForbidden},
+		{name: "missing token", origin: testOrigin, body: contextBody(testTarget), wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", origin: testOrigin, token: "ABCD-EFGH-2345-6780", body: contextBody(testTarget), wantStatus: http.StatusUnauthorized},
+		{name: "http target", origin: testOrigin, token: testToken, body: contextBody("http://claude.ai/api"), wantStatus: http.StatusBadRequest},
+		{name: "foreign host", origin: testOrigin, token: testToken, body: contextBody("https://evil.example/api"), wantStatus: http.StatusBadRequest},
+		{name: "lookalike host", origin: testOrigin, token: testToken, body: contextBody("https://notclaude.ai/api"), wantStatus: http.StatusBadRequest},
+		{name: "missing cookies", origin: testOrigin, token: testToken, body: `{"targetUrl":"` + testTarget + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "pairing disabled", disabled: true, body: contextBody(testTarget), wantStatus: http.StatusOK},
+		{name: "pairing disabled still checks target", disabled: true, body: contextBody("https://evil.example/api"), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, srv := newTestServer(t, !tt.disabled)
+			called := false
+			s.SetContextCallback(func(cookies, targetURL, organizationID string, headers map[string]string) {
+				called = true
+			})
+
+			resp := post(t, srv.URL+"/set-context", tt.origin, tt.token, tt.body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			accepted := tt.wantStatus == http.StatusOK
+			if called != accepted || s.HasContext() != accepted {
+				t.Errorf("callback called = %v, HasContext = %v, want %v", called, s.HasContext(), accepted)
+			}
+		})
+	}
+}
+
+func TestControlRequiresToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		disabled   bool
+		origin     string
+		token      string
+		action     string
+		wantStatus int
+	}{
+		{name: "cli with token", token: testToken, action: ActionRefresh, wantStatus: http.StatusOK},
+		{name: "cli without token", action: ActionRefresh, wantStatus: http.StatusUnauthorized},
+		{name: "extension origin without token", origin: testOrigin, action: ActionPause, wantStatus: http.StatusUnauthorized},
+		{name: "extension origin with wrong token", origin: testOrigin, token: "WXYZ-WXYZ-WXYZ-WXYZ", action: ActionPause, wantStatus: http.StatusUnauthorized},
+		{name: "extension origin with token", origin: testOrigin, token: testToken, action: ActionPause, wantStatus: http.StatusOK},
+		{name: "web page with token", origin: "https://evil.example", token: testToken, action: ActionPause, wantStatus: http.StatusForbidden},
+		{name: "pairing disabled still needs token", disabled: true, action: ActionResume, wantStatus: http.StatusUnauthorized},
+		{name: "unknown action", token: testToken, action: "reboot", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, srv := newTestServer(t, !tt.disabled)
+			var performed []string
+			s.SetControlHandler(func(action string) error {
+				if action == "reboot" {
+					return ErrUnknownAction
+				}
+				performed = append(performed, action)
+				return nil
+			})
+
+			resp := post(t, srv.URL+"/control/"+tt.action, tt.origin, tt.token, "")
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if ok := tt.wantStatus == http.StatusOK; ok != (len(performed) == 1) {
+				t.Errorf("performed = %v", performed)
+			}
+		})
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	_, srv := newTestServer(t, true)
+	if resp := post(t, srv.URL+"/control/refresh", "", testToken, ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("without handler: status = %d, want 503", resp.StatusCode)
+	}
+
+	s, srv := newTestServer(t, true)
+	s.SetControlHandler(func(string) error { return errors.New("no context") })
+	if resp := post(t, srv.URL+"/control/refresh", "", testToken, ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("failed action: status = %d, want 409", resp.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + "/control/refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestCORSAllowsOnlyExtensions(t *testing.T) {
+	_, srv := newTestServer(t, true)
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{testOrigin, testOrigin},
+		{"chrome-extension://abc", "chrome-extension://abc"},
+		{"https://evil.example", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodOptions, srv.URL+"/set-context", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.want {
+			t.Errorf("origin %q: Access-Control-Allow-Origin = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestHealthReportsPairing(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+		token    string
+		want     string
+	}{
+		{name: "valid token", token: testToken, want: `"paired":true`},
+		{name: "wrong token", token: "WXYZ", want: `"paired":false`},
+		{name: "no token", want: `"paired":false`},
+		{name: "pairing disabled", disabled: true, want: `"paired":true`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := newTestServer(t, !tt.disabled)
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/health", nil)
+			if tt.token != "" {
+				req.Header.Set(PairingHeader, tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body strings.Builder
+			if _, err := io.Copy(&body, resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(body.String(), tt.want) {
+				t.Errorf("body = %s, want %s", body.String(), tt.want)
+			}
+		})
+	}
+}
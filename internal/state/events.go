@@ -0,0 +1,91 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to subscribers
+const (
+	EventUsage        = "usage"        // Successful poll produced a new value
+	EventError        = "error"        // Error state changed (new error kind or status)
+	EventContext      = "context"      // Cookies received from the extension
+	EventNotification = "notification" // A desktop notification was shown
+	EventPaused       = "paused"       // Automatic polling paused or resumed
+)
+
+// subscriberBuffer is the number of events queued per subscriber before new ones are dropped
+const subscriberBuffer = 16
+
+// NotificationEvent describes a notification that fired
+type NotificationEvent struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Event is a state change delivered to subscribers
+// Data is a Snapshot for usage/error/context/paused events and a NotificationEvent for notifications
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// broker fans events out to subscribers without blocking publishers
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// Subscribe returns a channel receiving all future events and a function to unsubscribe
+// Slow subscribers miss events instead of blocking the poll loop
+func (s *Store) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	s.events.mu.Lock()
+	if s.events.subscribers == nil {
+		s.events.subscribers = make(map[chan Event]struct{})
+	}
+	s.events.subscribers[ch] = struct{}{}
+	s.events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.events.mu.Lock()
+			delete(s.events.subscribers, ch)
+			s.events.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to all subscribers
+func (s *Store) Publish(eventType string, data interface{}) {
+	event := Event{Type: eventType, Time: time.Now(), Data: data}
+
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	for ch := range s.events.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// PublishNotification records a notification in the history and sends it to all subscribers
+func (s *Store) PublishNotification(notificationType, title, message string) {
+	s.addNotification(NotificationRecord{
+		Time:    time.Now(),
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	})
+	s.Publish(EventNotification, NotificationEvent{
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	})
+}
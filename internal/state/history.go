@@ -0,0 +1,131 @@
+package state
+
+import (
+	"time"
+
+	"claudecompanion/internal/api"
+)
+
+// MaxHistoryAge is how long samples are kept in memory (the dashboard shows up to a week)
+const MaxHistoryAge = 7 * 24 * time.Hour
+
+// In-memory history limits
+const (
+	maxHistorySamples       = 20000
+	maxHistoryErrors        = 100
+	maxHistoryNotifications = 100
+)
+
+// Sample is the utilization of every window at the time of a successful poll
+type Sample struct {
+	Time        time.Time          `json:"time"`
+	Utilization map[string]float64 `json:"utilization"`
+}
+
+// ErrorRecord is a failed poll
+type ErrorRecord struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Message    string    `json:"message"`
+}
+
+// NotificationRecord is a notification that fired
+type NotificationRecord struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+}
+
+// History holds recent samples, errors and notifications, oldest first
+type History struct {
+	Samples       []Sample             `json:"samples"`
+	Errors        []ErrorRecord        `json:"errors"`
+	Notifications []NotificationRecord `json:"notifications"`
+}
+
+// History returns records newer than since
+func (s *Store) History(since time.Time) History {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h := History{
+		Samples:       []Sample{},
+		Errors:        []ErrorRecord{},
+		Notifications: []NotificationRecord{},
+	}
+	for _, sample := range s.history.Samples {
+		if !sample.Time.Before(since) {
+			h.Samples = append(h.Samples, sample)
+		}
+	}
+	for _, rec := range s.history.Errors {
+		if !rec.Time.Before(since) {
+			h.Errors = append(h.Errors, rec)
+		}
+	}
+	for _, rec := range s.history.Notifications {
+		if !rec.Time.Before(since) {
+			h.Notifications = append(h.Notifications, rec)
+		}
+	}
+	return h
+}
+
+// RestoreHistory replaces the in-memory history with records loaded from disk (oldest first)
+// Notifications aren't persisted and are kept as is
+func (s *Store) RestoreHistory(samples []Sample, errors []ErrorRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(samples) > maxHistorySamples {
+		samples = samples[len(samples)-maxHistorySamples:]
+	}
+	if len(errors) > maxHistoryErrors {
+		errors = errors[len(errors)-maxHistoryErrors:]
+	}
+	s.history.Samples = append([]Sample(nil), samples...)
+	s.history.Errors = append([]ErrorRecord(nil), errors...)
+}
+
+// addSample appends a usage sample (caller holds s.mu)
+func (s *Store) addSample(usage *api.UsageResponse, at time.Time) {
+	utilization := make(map[string]float64, len(usage.Windows))
+	for _, w := range usage.Windows {
+		utilization[w.Name] = w.Utilization
+	}
+	s.history.Samples = append(s.history.Samples, Sample{Time: at, Utilization: utilization})
+
+	// Drop samples older than the history window or over the limit
+	cutoff := at.Add(-MaxHistoryAge)
+	drop := 0
+	for drop < len(s.history.Samples) && s.history.Samples[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if over := len(s.history.Samples) - drop - maxHistorySamples; over > 0 {
+		drop += over
+	}
+	if drop > 0 {
+		s.history.Samples = append([]Sample(nil), s.history.Samples[drop:]...)
+	}
+}
+
+// addError appends a failed poll (caller holds s.mu)
+func (s *Store) addError(rec ErrorRecord) {
+	s.history.Errors = append(s.history.Errors, rec)
+	if over := len(s.history.Errors) - maxHistoryErrors; over > 0 {
+		s.history.Errors = append([]ErrorRecord(nil), s.history.Errors[over:]...)
+	}
+}
+
+// addNotification appends a notification that fired
+func (s *Store) addNotification(rec NotificationRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history.Notifications = append(s.history.Notifications, rec)
+	if over := len(s.history.Notifications) - maxHistoryNotifications; over > 0 {
+		s.history.Notifications = append([]NotificationRecord(nil), s.history.Notifications[over:]...)
+	}
+}
@@ -0,0 +1,202 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/forecast"
+)
+
+// WindowState is a single usage window as exposed by the local API
+type WindowState struct {
+	Name        string     `json:"name"`
+	Label       string     `json:"label"`
+	Utilization float64    `json:"utilization"`
+	Remaining   int        `json:"remaining"`
+	ResetsAt    *time.Time `json:"resets_at"`
+}
+
+// ErrorState describes the current error condition
+type ErrorState struct {
+	Kind       string    `json:"kind"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Message    string    `json:"message"`
+	Hint       string    `json:"hint"`
+	Since      time.Time `json:"since"`
+}
+
+// Snapshot is the latest known usage state shared between the poll loop and the local API
+type Snapshot struct {
+	Windows           []WindowState       `json:"windows"`
+	PrimaryWindow     string              `json:"primary_window"`
+	Remaining         int                 `json:"remaining"` // Remaining percent of primary window, -1 if unknown
+	ResetsAt          *time.Time          `json:"resets_at"`
+	Forecast          []forecast.Forecast `json:"forecast"` // Projected exhaustion of windows with enough samples
+	LastSuccess       *time.Time          `json:"last_success"`
+	LastAttempt       *time.Time          `json:"last_attempt"`
+	NextPoll          *time.Time          `json:"next_poll"`
+	HasContext        bool                `json:"has_context"`
+	ContextReceivedAt *time.Time          `json:"context_received_at"`
+	Error             *ErrorState         `json:"error"`
+	ConsecutiveErrors int                 `json:"consecutive_errors"`
+	Paused            bool                `json:"paused"` // Automatic polling is paused
+	Demo              bool                `json:"demo,omitempty"`
+}
+
+// Store holds the current Snapshot, safe for concurrent use
+type Store struct {
+	mu      sync.RWMutex
+	snap    Snapshot
+	history History
+	events  broker
+}
+
+// NewStore creates a store with unknown usage
+func NewStore() *Store {
+	return &Store{
+		snap: Snapshot{
+			Windows:   []WindowState{},
+			Forecast:  []forecast.Forecast{},
+			Remaining: -1,
+		},
+	}
+}
+
+// Snapshot returns a copy of the current state
+func (s *Store) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := s.snap
+	snap.Windows = append([]WindowState{}, s.snap.Windows...)
+	snap.Forecast = append([]forecast.Forecast{}, s.snap.Forecast...)
+	if s.snap.Error != nil {
+		errState := *s.snap.Error
+		snap.Error = &errState
+	}
+	return snap
+}
+
+// Usage converts the windows back to an API response with the primary window selected,
+// e.g. to format the tray tooltip outside of the app
+func (snap *Snapshot) Usage() *api.UsageResponse {
+	usage := &api.UsageResponse{Windows: make([]api.UsageWindow, 0, len(snap.Windows))}
+	for _, w := range snap.Windows {
+		usage.Windows = append(usage.Windows, api.UsageWindow{
+			Name:        w.Name,
+			Utilization: w.Utilization,
+			ResetsAt:    w.ResetsAt,
+		})
+	}
+	usage.SelectWindow(snap.PrimaryWindow)
+	return usage
+}
+
+// SetUsage records a successful poll result (usage must have its window selected)
+func (s *Store) SetUsage(usage *api.UsageResponse, at time.Time) {
+	windows := make([]WindowState, 0, len(usage.Windows))
+	for i := range usage.Windows {
+		w := &usage.Windows[i]
+		windows = append(windows, WindowState{
+			Name:        w.Name,
+			Label:       w.Label(),
+			Utilization: w.Utilization,
+			Remaining:   w.Remaining(),
+			ResetsAt:    w.ResetsAt,
+		})
+	}
+
+	s.mu.Lock()
+	s.snap.Windows = windows
+	s.snap.Remaining = usage.GetInvertedValue()
+	s.snap.ResetsAt = usage.GetResetTime()
+	if primary := usage.PrimaryWindow(); primary != nil {
+		s.snap.PrimaryWindow = primary.Name
+	}
+	s.snap.LastSuccess = &at
+	s.snap.LastAttempt = &at
+	s.snap.HasContext = true
+	s.snap.Error = nil
+	s.snap.ConsecutiveErrors = 0
+	s.addSample(usage, at)
+	s.mu.Unlock()
+
+	s.Publish(EventUsage, s.Snapshot())
+}
+
+// SetForecast records forecasts computed from recent polls
+// Call it before SetUsage so the usage event carries the new forecast
+func (s *Store) SetForecast(forecasts []forecast.Forecast) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Forecast = append([]forecast.Forecast{}, forecasts...)
+}
+
+// SetError records a failed poll
+// An error event is published only when the error kind or status changes
+func (s *Store) SetError(fetchErr *api.FetchError, consecutiveErrors int, at time.Time) {
+	s.mu.Lock()
+	since := at
+	changed := true
+	if prev := s.snap.Error; prev != nil {
+		since = prev.Since
+		changed = prev.Kind != fetchErr.Kind.String() || prev.StatusCode != fetchErr.StatusCode
+	}
+	s.snap.Error = &ErrorState{
+		Kind:       fetchErr.Kind.String(),
+		StatusCode: fetchErr.StatusCode,
+		Message:    fetchErr.Description(),
+		Hint:       fetchErr.Hint(),
+		Since:      since,
+	}
+	s.snap.ConsecutiveErrors = consecutiveErrors
+	s.snap.LastAttempt = &at
+	s.addError(ErrorRecord{
+		Time:       at,
+		Kind:       fetchErr.Kind.String(),
+		StatusCode: fetchErr.StatusCode,
+		Message:    fetchErr.Description(),
+	})
+	s.mu.Unlock()
+
+	if changed {
+		s.Publish(EventError, s.Snapshot())
+	}
+}
+
+// SetNextPoll records when the next automatic poll happens
+func (s *Store) SetNextPoll(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.NextPoll = &at
+}
+
+// SetContextReceived records that cookies were received from the extension
+// A new context also clears the error state (the app resets its error counter)
+func (s *Store) SetContextReceived(at time.Time) {
+	s.mu.Lock()
+	s.snap.HasContext = true
+	s.snap.ContextReceivedAt = &at
+	s.snap.Error = nil
+	s.snap.ConsecutiveErrors = 0
+	s.mu.Unlock()
+
+	s.Publish(EventContext, s.Snapshot())
+}
+
+// SetPaused records whether automatic polling is paused
+func (s *Store) SetPaused(paused bool) {
+	s.mu.Lock()
+	s.snap.Paused = paused
+	s.mu.Unlock()
+
+	s.Publish(EventPaused, s.Snapshot())
+}
+
+// SetDemo marks the state as produced by demo mode
+func (s *Store) SetDemo(demo bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Demo = demo
+}
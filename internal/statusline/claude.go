@@ -0,0 +1,90 @@
+package statusline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"claudecompanion/internal/api"
+	"claudecompanion/internal/icon"
+	"claudecompanion/internal/state"
+)
+
+// ANSI colors of the Claude Code status line, they follow the terminal theme
+var ansiColors = map[icon.ColorMode]string{
+	icon.ColorGreen:  "\x1b[32m",
+	icon.ColorYellow: "\x1b[33m",
+	icon.ColorRed:    "\x1b[31m",
+	icon.ColorGray:   "\x1b[90m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// ClaudeCodeOptions control the Claude Code status line
+type ClaudeCodeOptions struct {
+	Windows []string // Window names to show in this order, empty = all
+	Color   bool     // Use ANSI colors
+	Prefix  string   // Text before the windows, e.g. the model name
+}
+
+// ClaudeCode formats a compact line for the Claude Code status line: "5h 42% ↻16:05 | 7d 71%"
+// The reset time is shown for windows resetting within a day
+func ClaudeCode(snap *state.Snapshot, opts ClaudeCodeOptions) string {
+	paint := func(mode icon.ColorMode, text string) string {
+		if !opts.Color {
+			return text
+		}
+		return ansiColors[mode] + text + ansiReset
+	}
+
+	var parts []string
+	for _, w := range selectWindows(snap.Windows, opts.Windows) {
+		part := fmt.Sprintf("%s %d%%", shortLabel(w.Name), w.Remaining)
+		if w.ResetsAt != nil && time.Until(*w.ResetsAt) < 24*time.Hour {
+			part += " ↻" + api.FormatResetTime(w.ResetsAt)
+		}
+		parts = append(parts, paint(icon.GetColorMode(w.Remaining, snap.Error != nil), part))
+	}
+
+	line := strings.Join(parts, " | ")
+	if line == "" {
+		line = paint(icon.ColorGray, "—")
+	}
+	if snap.Paused {
+		line = "⏸ " + line
+	}
+	if snap.Error != nil {
+		line += " " + paint(icon.ColorGray, "⚠ "+snap.Error.Kind)
+	}
+	return opts.Prefix + line
+}
+
+// ClaudeCodeOffline is shown when the app isn't running
+func ClaudeCodeOffline(opts ClaudeCodeOptions) string {
+	if !opts.Color {
+		return opts.Prefix + "—"
+	}
+	return opts.Prefix + ansiColors[icon.ColorGray] + "—" + ansiReset
+}
+
+// selectWindows returns the windows listed in names, in that order; all windows if names is empty
+func selectWindows(windows []state.WindowState, names []string) []state.WindowState {
+	if len(names) == 0 {
+		return windows
+	}
+	var result []state.WindowState
+	for _, name := range names {
+		for _, w := range windows {
+			if w.Name == name {
+				result = append(result, w)
+			}
+		}
+	}
+	return result
+}
+
+// shortLabel returns an ASCII window label for terminals: five_hour -> 5h, seven_day_opus -> 7d opus
+func shortLabel(name string) string {
+	replacer := strings.NewReplacer(api.WindowSevenDay, "7d", api.WindowFiveHour, "5h", "_", " ")
+	return replacer.Replace(name)
+}
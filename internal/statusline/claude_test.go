@@ -0,0 +1,77 @@
+package statusline
+
+import (
+	"testing"
+	"time"
+
+	"claudecompanion/internal/state"
+)
+
+func TestClaudeCode(t *testing.T) {
+	soon := time.Now().Add(2 * time.Hour)
+	later := time.Now().Add(72 * time.Hour)
+	windows := []state.WindowState{
+		{Name: "five_hour", Remaining: 42, ResetsAt: &soon},
+		{Name: "seven_day", Remaining: 15, ResetsAt: &later},
+		{Name: "seven_day_opus", Remaining: 90},
+	}
+	resetSoon := soon.Local().Format("15:04")
+
+	tests := []struct {
+		name string
+		snap *state.Snapshot
+		opts ClaudeCodeOptions
+		want string
+	}{
+		{
+			name: "all windows",
+			snap: &state.Snapshot{Windows: windows},
+			want: "5h 42% ↻" + resetSoon + " | 7d 15% | 7d opus 90%",
+		},
+		{
+			name: "selected windows in order",
+			snap: &state.Snapshot{Windows: windows},
+			opts: ClaudeCodeOptions{Windows: []string{"seven_day", "five_hour", "missing"}},
+			want: "7d 15% | 5h 42% ↻" + resetSoon,
+		},
+		{
+			name: "colors and prefix",
+			snap: &state.Snapshot{Windows: windows[1:]},
+			opts: ClaudeCodeOptions{Color: true, Prefix: "Opus · "},
+			want: "Opus · \x1b[31m7d 15%\x1b[0m | \x1b[32m7d opus 90%\x1b[0m",
+		},
+		{
+			name: "error is gray",
+			snap: &state.Snapshot{Windows: windows[2:], Error: &state.ErrorState{Kind: "network"}},
+			opts: ClaudeCodeOptions{Color: true},
+			want: "\x1b[90m7d opus 90%\x1b[0m \x1b[90m⚠ network\x1b[0m",
+		},
+		{
+			name: "paused",
+			snap: &state.Snapshot{Windows: windows[2:], Paused: true},
+			want: "⏸ 7d opus 90%",
+		},
+		{
+			name: "no windows",
+			snap: &state.Snapshot{},
+			want: "—",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClaudeCode(tt.snap, tt.opts); got != tt.want {
+				t.Errorf("ClaudeCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaudeCodeOffline(t *testing.T) {
+	if got := ClaudeCodeOffline(ClaudeCodeOptions{Prefix: "> "}); got != "> —" {
+		t.Errorf("ClaudeCodeOffline() = %q, want %q", got, "> —")
+	}
+	if got, want := ClaudeCodeOffline(ClaudeCodeOptions{Color: true}), "\x1b[90m—\x1b[0m"; got != want {
+		t.Errorf("ClaudeCodeOffline() = %q, want %q", got, want)
+	}
+}
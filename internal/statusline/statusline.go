@@ -0,0 +1,172 @@
+package statusline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/icon"
+	"claudecompanion/internal/state"
+)
+
+// Output formats of status bars
+const (
+	FormatWaybar   = "waybar"   // JSON object for a custom module (return-type: json)
+	FormatI3blocks = "i3blocks" // full_text, short_text and color lines
+	FormatPolybar  = "polybar"  // Text with %{F#rrggbb} color tags
+	FormatTmux     = "tmux"     // Text with #[fg=#rrggbb] style
+	FormatPlain    = "plain"    // Text only
+)
+
+// Formats lists all supported formats
+var Formats = []string{FormatWaybar, FormatI3blocks, FormatPolybar, FormatTmux, FormatPlain}
+
+// IsFormat returns true if format is supported
+func IsFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is the state of the primary window prepared for a status bar
+type Status struct {
+	Text       string // "42%", "—" when unknown
+	Percentage int    // Remaining percent, -1 when unknown
+	Mode       icon.ColorMode
+	Color      config.ColorRGB
+	Tooltip    string // Same as the tray tooltip, lines separated by \n; shows the time of the last successful poll
+}
+
+// Class returns the CSS class: green, yellow, red or gray
+func (s Status) Class() string {
+	return s.Mode.String()
+}
+
+// hexColor returns the color as #rrggbb
+func (s Status) hexColor() string {
+	return fmt.Sprintf("#%02x%02x%02x", s.Color.R, s.Color.G, s.Color.B)
+}
+
+// New prepares the status of a snapshot using the tray icon thresholds and colors
+func New(snap *state.Snapshot, colors *config.IconColors) Status {
+	status := Status{Percentage: snap.Remaining}
+
+	var lines []string
+	switch {
+	case !snap.HasContext:
+		status.Percentage = -1
+		lines = append(lines, "Ожидаю куки от расширения")
+	case len(snap.Windows) == 0:
+		status.Percentage = -1
+		lines = append(lines, "Нет данных о лимитах")
+	default:
+		lines = append(lines, snap.Usage().TooltipLines()...)
+		// The data may be older than this render, so show when it was fetched
+		if snap.LastSuccess != nil {
+			lines = append(lines, "Обновлено: "+snap.LastSuccess.Local().Format("15:04 02.01"))
+		}
+		for i := range snap.Forecast {
+			f := &snap.Forecast[i]
+			if f.Window == snap.PrimaryWindow && f.BeforeReset && snap.Remaining > 0 {
+				lines = append(lines, f.TooltipLine())
+			}
+		}
+	}
+	if snap.Error != nil {
+		lines = append(lines, snap.Error.Message)
+		if snap.Error.Hint != "" {
+			lines = append(lines, snap.Error.Hint)
+		}
+	}
+	if snap.Paused {
+		lines = append(lines, "Автоматический опрос приостановлен")
+	}
+
+	if status.Percentage < 0 {
+		status.Text = "—"
+	} else {
+		status.Text = fmt.Sprintf("%d%%", status.Percentage)
+	}
+	status.Mode = icon.GetColorMode(status.Percentage, snap.Error != nil || status.Percentage < 0)
+	status.Color = icon.ModeColor(colors, status.Mode)
+	status.Tooltip = strings.Join(lines, "\n")
+	return status
+}
+
+// Offline is shown when the app isn't running
+func Offline(colors *config.IconColors) Status {
+	return Status{
+		Text:       "—",
+		Percentage: -1,
+		Mode:       icon.ColorGray,
+		Color:      icon.ModeColor(colors, icon.ColorGray),
+		Tooltip:    "ClaudeCompanion не запущен",
+	}
+}
+
+// Render formats the status for a status bar, one update per call
+// Every format ends with a newline; i3blocks prints three lines
+func Render(format string, s Status) string {
+	hex := s.hexColor()
+	switch format {
+	case FormatWaybar:
+		data, _ := json.Marshal(waybarOutput{
+			Text:       s.Text,
+			Tooltip:    s.Tooltip,
+			Class:      s.Class(),
+			Alt:        s.Class(),
+			Percentage: clampPercentage(s.Percentage),
+		})
+		return string(data) + "\n"
+	case FormatI3blocks:
+		return s.Text + "\n" + s.Text + "\n" + hex + "\n"
+	case FormatPolybar:
+		return "%{F" + hex + "}" + s.Text + "%{F-}\n"
+	case FormatTmux:
+		return "#[fg=" + hex + "]" + s.Text + "#[default]\n"
+	default:
+		return s.Text + "\n"
+	}
+}
+
+// RenderStream formats the status as a single line for long-running bar modules
+// (waybar exec, polybar tail, i3blocks interval=persist with format=json)
+func RenderStream(format string, s Status) string {
+	if format != FormatI3blocks {
+		return Render(format, s)
+	}
+	data, _ := json.Marshal(i3blocksOutput{
+		FullText:  s.Text,
+		ShortText: s.Text,
+		Color:     s.hexColor(),
+	})
+	return string(data) + "\n"
+}
+
+// i3blocksOutput is a block update of i3blocks with format=json
+type i3blocksOutput struct {
+	FullText  string `json:"full_text"`
+	ShortText string `json:"short_text"`
+	Color     string `json:"color"`
+}
+
+// waybarOutput is the JSON expected by waybar custom modules
+type waybarOutput struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Alt        string `json:"alt"`
+	Percentage int    `json:"percentage"`
+}
+
+// clampPercentage maps unknown (-1) to 0 for bars drawing format-icons
+func clampPercentage(p int) int {
+	if p < 0 {
+		return 0
+	}
+	return p
+}
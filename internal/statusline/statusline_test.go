@@ -0,0 +1,205 @@
+package statusline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"claudecompanion/internal/config"
+	"claudecompanion/internal/forecast"
+	"claudecompanion/internal/icon"
+	"claudecompanion/internal/state"
+)
+
+var testColors = &config.IconColors{
+	Green:  config.ColorRGB{R: 0x2e, G: 0xcc, B: 0x71},
+	Yellow: config.ColorRGB{R: 0xf1, G: 0xc4, B: 0x0f},
+	Red:    config.ColorRGB{R: 0xe7, G: 0x4c, B: 0x3c},
+	Gray:   config.ColorRGB{R: 0x80, G: 0x80, B: 0x80},
+}
+
+func testSnapshot(remaining int) *state.Snapshot {
+	resetsAt := time.Now().Add(2 * time.Hour)
+	exhaustsAt := resetsAt.Add(-time.Hour)
+	lastSuccess := time.Now().Add(-3 * time.Hour)
+	return &state.Snapshot{
+		Windows: []state.WindowState{
+			{Name: "five_hour", Label: "5ч", Utilization: float64(100 - remaining), Remaining: remaining, ResetsAt: &resetsAt},
+			{Name: "seven_day", Label: "7д", Utilization: 29, Remaining: 71},
+		},
+		PrimaryWindow: "five_hour",
+		Remaining:     remaining,
+		Forecast: []forecast.Forecast{
+			{Window: "five_hour", ExhaustsAt: &exhaustsAt, ResetsAt: &resetsAt, BeforeReset: true},
+			{Window: "seven_day", ExhaustsAt: &exhaustsAt, ResetsAt: &resetsAt, BeforeReset: true},
+		},
+		LastSuccess: &lastSuccess,
+		HasContext:  true,
+	}
+}
+
+func TestNew(t *testing.T) {
+	stale := time.Now().Add(-3 * time.Hour).Local().Format("15:04 02.01")
+
+	tests := []struct {
+		name        string
+		snap        *state.Snapshot
+		wantText    string
+		wantMode    icon.ColorMode
+		wantTooltip []string
+		wantNot     []string
+	}{
+		{
+			name:        "green",
+			snap:        testSnapshot(42),
+			wantText:    "42%",
+			wantMode:    icon.ColorGreen,
+			wantTooltip: []string{"5ч: 58% (", "7д: 29% (—)", "Обновлено: " + stale, "Кончится в "},
+		},
+		{name: "yellow", snap: testSnapshot(30), wantText: "30%", wantMode: icon.ColorYellow},
+		{name: "red", snap: testSnapshot(10), wantText: "10%", wantMode: icon.ColorRed},
+		{
+			name:     "exhausted hides the forecast",
+			snap:     testSnapshot(0),
+			wantText: "0%",
+			wantMode: icon.ColorRed,
+			wantNot:  []string{"Кончится"},
+		},
+		{
+			name: "error",
+			snap: func() *state.Snapshot {
+				s := testSnapshot(42)
+				s.Error = &state.ErrorState{Message: "Сессия истекла", Hint: "Откройте claude.ai"}
+				return s
+			}(),
+			wantText:    "42%",
+			wantMode:    icon.ColorGray,
+			wantTooltip: []string{"Сессия истекла\nОткройте claude.ai"},
+		},
+		{
+			name:        "waiting for context",
+			snap:        &state.Snapshot{Remaining: -1},
+			wantText:    "—",
+			wantMode:    icon.ColorGray,
+			wantTooltip: []string{"Ожидаю куки от расширения"},
+			wantNot:     []string{"Обновлено"},
+		},
+		{
+			name:        "no windows",
+			snap:        &state.Snapshot{HasContext: true, Remaining: 50},
+			wantText:    "—",
+			wantMode:    icon.ColorGray,
+			wantTooltip: []string{"Нет данных о лимитах"},
+		},
+		{
+			name: "paused",
+			snap: func() *state.Snapshot {
+				s := testSnapshot(42)
+				s.Paused = true
+				return s
+			}(),
+			wantText:    "42%",
+			wantMode:    icon.ColorGreen,
+			wantTooltip: []string{"Автоматический опрос приостановлен"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := New(tt.snap, testColors)
+			if status.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", status.Text, tt.wantText)
+			}
+			if status.Mode != tt.wantMode || status.Color != icon.ModeColor(testColors, tt.wantMode) {
+				t.Errorf("Mode = %s, Color = %v, want %s", status.Mode, status.Color, tt.wantMode)
+			}
+			if strings.Contains(status.Tooltip, "\r") {
+				t.Errorf("Tooltip contains \\r: %q", status.Tooltip)
+			}
+			for _, want := range tt.wantTooltip {
+				if !strings.Contains(status.Tooltip, want) {
+					t.Errorf("Tooltip doesn't contain %q:\n%s", want, status.Tooltip)
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(status.Tooltip, unwanted) {
+					t.Errorf("Tooltip contains %q:\n%s", unwanted, status.Tooltip)
+				}
+			}
+			// Only the forecast of the primary window is shown
+			if strings.Count(status.Tooltip, "Кончится") > 1 {
+				t.Errorf("Tooltip shows forecasts of other windows:\n%s", status.Tooltip)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	status := Status{Text: "42%", Percentage: 42, Mode: icon.ColorGreen, Color: testColors.Green, Tooltip: "5ч: 58%\nОбновлено: 10:00 02.01"}
+	unknown := Offline(testColors)
+
+	tests := []struct {
+		format string
+		status Status
+		want   string
+	}{
+		{FormatI3blocks, status, "42%\n42%\n#2ecc71\n"},
+		{FormatPolybar, status, "%{F#2ecc71}42%%{F-}\n"},
+		{FormatTmux, status, "#[fg=#2ecc71]42%#[default]\n"},
+		{FormatPlain, status, "42%\n"},
+		{FormatPlain, unknown, "—\n"},
+		{FormatTmux, unknown, "#[fg=#808080]—#[default]\n"},
+		{
+			FormatWaybar,
+			status,
+			`{"text":"42%","tooltip":"5ч: 58%\nОбновлено: 10:00 02.01","class":"green","alt":"green","percentage":42}` + "\n",
+		},
+		{
+			FormatWaybar,
+			unknown,
+			`{"text":"—","tooltip":"ClaudeCompanion не запущен","class":"gray","alt":"gray","percentage":0}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Render(tt.format, tt.status); got != tt.want {
+			t.Errorf("Render(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRenderStream(t *testing.T) {
+	status := Status{Text: "10%", Percentage: 10, Mode: icon.ColorRed, Color: testColors.Red}
+
+	var block map[string]string
+	line := RenderStream(FormatI3blocks, status)
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("i3blocks stream is not a single line: %q", line)
+	}
+	if err := json.Unmarshal([]byte(line), &block); err != nil {
+		t.Fatalf("i3blocks stream is not JSON: %v", err)
+	}
+	if block["full_text"] != "10%" || block["short_text"] != "10%" || block["color"] != "#e74c3c" {
+		t.Errorf("i3blocks block = %v", block)
+	}
+
+	for _, format := range []string{FormatWaybar, FormatPolybar, FormatTmux, FormatPlain} {
+		if got := RenderStream(format, status); got != Render(format, status) {
+			t.Errorf("RenderStream(%s) = %q, want the Render output", format, got)
+		}
+	}
+}
+
+func TestIsFormat(t *testing.T) {
+	for _, format := range Formats {
+		if !IsFormat(format) {
+			t.Errorf("IsFormat(%q) = false", format)
+		}
+	}
+	for _, format := range []string{"", "json", "Waybar"} {
+		if IsFormat(format) {
+			t.Errorf("IsFormat(%q) = true", format)
+		}
+	}
+}
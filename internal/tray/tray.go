@@ -5,6 +5,7 @@ import (
 	"log"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"claudecompanion/internal/config"
 	"claudecompanion/internal/icon"
@@ -22,6 +23,8 @@ type TrayManager struct {
 	targetURL      string
 	configPath     string
 	browserPath    string
+	pairingToken   string
+	dashboardURL   string
 	onExit         func()
 	onOpenSettings func()
 	onClick        func()
@@ -65,6 +68,16 @@ func (t *TrayManager) SetBrowserPath(path string) {
 	t.browserPath = path
 }
 
+// SetPairingToken sets the pairing code shown in the menu (must be called before Initialize)
+func (t *TrayManager) SetPairingToken(token string) {
+	t.pairingToken = token
+}
+
+// SetDashboardURL sets the address of the local web dashboard (must be called before Initialize)
+func (t *TrayManager) SetDashboardURL(url string) {
+	t.dashboardURL = url
+}
+
 // Initialize sets up the system tray
 func (t *TrayManager) Initialize() {
 	// Set initial tooltip and icon
@@ -77,6 +90,15 @@ func (t *TrayManager) Initialize() {
 	// Create menu items
 	mOpenClaude := systray.AddMenuItem("Открыть Claude.ai", "Открыть сайт Claude.ai в браузере")
 	mRefresh := systray.AddMenuItem("Получить статистику", "Обновить статистику сейчас")
+	mDashboard := systray.AddMenuItem("Открыть панель", "Открыть панель с графиками в браузере")
+	if t.dashboardURL == "" {
+		mDashboard.Hide()
+	}
+	systray.AddSeparator()
+	mPairing := systray.AddMenuItem("Код сопряжения: "+t.pairingToken, "Скопировать код для настроек расширения")
+	if t.pairingToken == "" {
+		mPairing.Hide()
+	}
 	systray.AddSeparator()
 	mOpenSettings := systray.AddMenuItem("Открыть настройки", "Открыть конфигурационный файл")
 	systray.AddSeparator()
@@ -92,6 +114,14 @@ func (t *TrayManager) Initialize() {
 				if t.onRefresh != nil {
 					t.onRefresh()
 				}
+			case <-mDashboard.ClickedCh:
+				t.openURL(t.dashboardURL)
+			case <-mPairing.ClickedCh:
+				if err := copyToClipboard(t.pairingToken); err != nil {
+					log.Printf("Failed to copy pairing code: %v", err)
+				} else {
+					log.Println("Pairing code copied to clipboard")
+				}
 			case <-mOpenSettings.ClickedCh:
 				t.handleOpenSettings()
 			case <-mQuit.ClickedCh:
@@ -207,6 +237,33 @@ func (t *TrayManager) openFileInEditor(path string) error {
 	return cmd.Start()
 }
 
+// copyToClipboard copies text to the system clipboard using platform tools
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("clip")
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	default: // Linux and others
+		// Try Wayland first, then X11 tools
+		tools := [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+		for _, tool := range tools {
+			if _, err := exec.LookPath(tool[0]); err == nil {
+				cmd = exec.Command(tool[0], tool[1:]...)
+				break
+			}
+		}
+		if cmd == nil {
+			return fmt.Errorf("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+		}
+	}
+
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
 // HandleClick handles icon click events
 func (t *TrayManager) HandleClick() {
 	if t.onClick != nil {